@@ -0,0 +1,232 @@
+package keyformat
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"util.tim/encrypto/core/asymetric"
+)
+
+const (
+	TypeRSA     = "rsa"
+	TypeEd25519 = "ed25519"
+	TypeX25519  = "x25519"
+
+	// FormatPEM is PKCS8 for private keys and PKIX for public keys, which
+	// is what the rest of encrypto reads and writes.
+	FormatPEM     = "pem"
+	FormatPKCS1   = "pkcs1"
+	FormatPKCS8   = "pkcs8"
+	FormatPKIX    = "pkix"
+	FormatJWK     = "jwk"
+	FormatOpenSSH = "openssh"
+
+	MinRSABits     = 2048
+	MaxRSABits     = 8192
+	DefaultRSABits = 3072
+)
+
+var (
+	Types   = []string{TypeRSA, TypeEd25519, TypeX25519}
+	Formats = []string{FormatPEM, FormatPKCS1, FormatPKCS8, FormatPKIX, FormatJWK, FormatOpenSSH}
+)
+
+type Key interface {
+	Type() string
+	// Bits is the modulus size of RSA keys and the curve size otherwise.
+	Bits() int
+	IsPrivate() bool
+	Public() Key
+	Encode(format string) ([]byte, error)
+	// PublicKeyBytes is the PKIX encoding of the public half.
+	PublicKeyBytes() []byte
+	Fingerprint() string
+	// SSHFingerprint is the fingerprint ssh-keygen -l prints, or "" for key
+	// types OpenSSH does not know.
+	SSHFingerprint() string
+}
+
+// x25519 keys have no type in the standard library, so they are kept as
+// raw 32 byte strings.
+type x25519PrivateKey []byte
+
+type x25519PublicKey []byte
+
+type keyPair struct {
+	keyType    string
+	privateKey interface{}
+	publicKey  interface{}
+}
+
+func (key *keyPair) Type() string {
+	return key.keyType
+}
+
+func (key *keyPair) Bits() int {
+	if publicKey, ok := key.publicKey.(*rsa.PublicKey); ok {
+		return publicKey.N.BitLen()
+	}
+
+	return 256
+}
+
+func (key *keyPair) IsPrivate() bool {
+	return key.privateKey != nil
+}
+
+func (key *keyPair) Public() Key {
+	return &keyPair{keyType: key.keyType, publicKey: key.publicKey}
+}
+
+func (key *keyPair) PublicKeyBytes() []byte {
+	der, err := marshalPKIX(key.publicKey)
+	if err != nil {
+		return nil
+	}
+
+	return der
+}
+
+func (key *keyPair) Fingerprint() string {
+	return asymetric.Fingerprint(key.PublicKeyBytes())
+}
+
+func (key *keyPair) SSHFingerprint() string {
+	publicKey, err := sshPublicKey(key.publicKey)
+	if err != nil {
+		return ""
+	}
+
+	return fingerprintSSH(publicKey)
+}
+
+func (key *keyPair) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatPEM:
+		if key.IsPrivate() {
+			return key.Encode(FormatPKCS8)
+		}
+
+		return key.Encode(FormatPKIX)
+	case FormatPKCS1:
+		return encodePKCS1(key)
+	case FormatPKCS8:
+		if !key.IsPrivate() {
+			return nil, errors.New("PKCS8 holds private keys, use pkix for a public key")
+		}
+
+		return encodePKCS8(key.privateKey)
+	case FormatPKIX:
+		return encodePKIX(key.publicKey)
+	case FormatJWK:
+		return encodeJWK(key)
+	case FormatOpenSSH:
+		if key.IsPrivate() {
+			return encodeOpenSSHPrivate(key.privateKey)
+		}
+
+		return encodeOpenSSHPublic(key.publicKey)
+	default:
+		return nil, fmt.Errorf("unknown key format [%s]", format)
+	}
+}
+
+func newKey(privateKey interface{}) (Key, error) {
+	switch private := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &keyPair{keyType: TypeRSA, privateKey: private, publicKey: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &keyPair{keyType: TypeEd25519, privateKey: private, publicKey: private.Public()}, nil
+	case *ed25519.PrivateKey:
+		return newKey(*private)
+	case x25519PrivateKey:
+		publicKey, err := curve25519.X25519(private, curve25519.Basepoint)
+		if err != nil {
+			return nil, err
+		}
+
+		return &keyPair{keyType: TypeX25519, privateKey: private, publicKey: x25519PublicKey(publicKey)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key [%T]", privateKey)
+	}
+}
+
+func newPublicKey(publicKey interface{}) (Key, error) {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return &keyPair{keyType: TypeRSA, publicKey: publicKey}, nil
+	case ed25519.PublicKey:
+		return &keyPair{keyType: TypeEd25519, publicKey: publicKey}, nil
+	case x25519PublicKey:
+		return &keyPair{keyType: TypeX25519, publicKey: publicKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key [%T]", publicKey)
+	}
+}
+
+// Generate creates a keypair. Bits only applies to RSA, where 0 picks
+// DefaultRSABits.
+func Generate(keyType string, bits int) (Key, error) {
+	switch keyType {
+	case TypeRSA:
+		if bits == 0 {
+			bits = DefaultRSABits
+		}
+
+		if bits < MinRSABits || bits > MaxRSABits {
+			return nil, fmt.Errorf("RSA keys must be between [%d] and [%d] bits, received [%d]", MinRSABits, MaxRSABits, bits)
+		}
+
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+
+		return newKey(privateKey)
+	case TypeEd25519, TypeX25519:
+		if bits != 0 && bits != 256 {
+			return nil, fmt.Errorf("[%s] keys are always 256 bits", keyType)
+		}
+
+		if keyType == TypeEd25519 {
+			_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+
+			return newKey(privateKey)
+		}
+
+		privateKey := make([]byte, curve25519.ScalarSize)
+		if _, err := io.ReadFull(rand.Reader, privateKey); err != nil {
+			return nil, err
+		}
+
+		return newKey(x25519PrivateKey(privateKey))
+	default:
+		return nil, fmt.Errorf("unknown key type [%s]", keyType)
+	}
+}
+
+// Parse reads a private or public key in any of Formats, telling them apart
+// by their shape.
+func Parse(data []byte) (Key, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return parseJWK(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN ")):
+		return parsePEM(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("ssh-")):
+		return parseOpenSSHPublic(trimmed)
+	default:
+		return nil, errors.New("expected a PEM, JWK or OpenSSH key")
+	}
+}
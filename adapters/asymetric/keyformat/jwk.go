@@ -0,0 +1,230 @@
+package keyformat
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// jwk covers the RSA and OKP members of RFC 7517, RFC 7518 and RFC 8037.
+type jwk struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	D       string `json:"d,omitempty"`
+	P       string `json:"p,omitempty"`
+	Q       string `json:"q,omitempty"`
+	DP      string `json:"dp,omitempty"`
+	DQ      string `json:"dq,omitempty"`
+	QI      string `json:"qi,omitempty"`
+	// Other holds the extra primes of a multi-prime key, which are not
+	// supported.
+	Other json.RawMessage `json:"oth,omitempty"`
+}
+
+func encodeBase64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func encodeInt(value *big.Int) string {
+	return encodeBase64(value.Bytes())
+}
+
+func encodeJWK(key *keyPair) ([]byte, error) {
+	var encoded jwk
+
+	switch publicKey := key.publicKey.(type) {
+	case *rsa.PublicKey:
+		encoded = jwk{KeyType: "RSA", N: encodeInt(publicKey.N), E: encodeInt(big.NewInt(int64(publicKey.E)))}
+
+		if privateKey, ok := key.privateKey.(*rsa.PrivateKey); ok {
+			if len(privateKey.Primes) != 2 {
+				return nil, errors.New("multi-prime RSA keys can not be written as JWK")
+			}
+
+			privateKey.Precompute()
+			encoded.D = encodeInt(privateKey.D)
+			encoded.P = encodeInt(privateKey.Primes[0])
+			encoded.Q = encodeInt(privateKey.Primes[1])
+			encoded.DP = encodeInt(privateKey.Precomputed.Dp)
+			encoded.DQ = encodeInt(privateKey.Precomputed.Dq)
+			encoded.QI = encodeInt(privateKey.Precomputed.Qinv)
+		}
+	case ed25519.PublicKey:
+		encoded = jwk{KeyType: "OKP", Curve: "Ed25519", X: encodeBase64(publicKey)}
+
+		if privateKey, ok := key.privateKey.(ed25519.PrivateKey); ok {
+			encoded.D = encodeBase64(privateKey.Seed())
+		}
+	case x25519PublicKey:
+		encoded = jwk{KeyType: "OKP", Curve: "X25519", X: encodeBase64(publicKey)}
+
+		if privateKey, ok := key.privateKey.(x25519PrivateKey); ok {
+			encoded.D = encodeBase64(privateKey)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported public key [%T]", key.publicKey)
+	}
+
+	data, err := json.MarshalIndent(encoded, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(data, '\n'), nil
+}
+
+func decodeBase64(name string, value string, size int) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the [%s] member: %w", name, err)
+	}
+
+	if size > 0 && len(decoded) != size {
+		return nil, fmt.Errorf("the [%s] member is [%d] bytes, expected [%d]", name, len(decoded), size)
+	}
+
+	return decoded, nil
+}
+
+func decodeInt(name string, value string) (*big.Int, error) {
+	decoded, err := decodeBase64(name, value, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+func parseRSAJWK(encoded jwk) (Key, error) {
+	n, err := decodeInt("n", encoded.N)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decodeInt("e", encoded.E)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("the RSA exponent is too large")
+	}
+
+	publicKey := rsa.PublicKey{N: n, E: int(e.Int64())}
+	if encoded.D == "" {
+		return newPublicKey(&publicKey)
+	}
+
+	if len(encoded.Other) > 0 {
+		return nil, errors.New("multi-prime RSA keys are not supported")
+	}
+
+	privateKey := &rsa.PrivateKey{PublicKey: publicKey}
+	if privateKey.D, err = decodeInt("d", encoded.D); err != nil {
+		return nil, err
+	}
+
+	p, err := decodeInt("p", encoded.P)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := decodeInt("q", encoded.Q)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey.Primes = []*big.Int{p, q}
+	if err = privateKey.Validate(); err != nil {
+		return nil, err
+	}
+	privateKey.Precompute()
+
+	return newKey(privateKey)
+}
+
+func parseOKPJWK(encoded jwk) (Key, error) {
+	x, err := decodeBase64("x", encoded.X, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoded.Curve {
+	case "Ed25519":
+		if encoded.D == "" {
+			return newPublicKey(ed25519.PublicKey(x))
+		}
+
+		seed, err := decodeBase64("d", encoded.D, ed25519.SeedSize)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := newKey(ed25519.NewKeyFromSeed(seed))
+		if err != nil {
+			return nil, err
+		}
+
+		return withPublic(key, x)
+	case "X25519":
+		if encoded.D == "" {
+			return newPublicKey(x25519PublicKey(x))
+		}
+
+		d, err := decodeBase64("d", encoded.D, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := newKey(x25519PrivateKey(d))
+		if err != nil {
+			return nil, err
+		}
+
+		return withPublic(key, x)
+	default:
+		return nil, fmt.Errorf("unsupported curve [%s]", encoded.Curve)
+	}
+}
+
+// withPublic makes sure the public key derived from d is the one in x, so a
+// mismatched JWK is rejected instead of silently using one half.
+func withPublic(key Key, x []byte) (Key, error) {
+	var publicKey []byte
+	switch public := key.(*keyPair).publicKey.(type) {
+	case ed25519.PublicKey:
+		publicKey = public
+	case x25519PublicKey:
+		publicKey = public
+	}
+
+	if !bytes.Equal(publicKey, x) {
+		return nil, errors.New("the public key in the JWK does not match the private key")
+	}
+
+	return key, nil
+}
+
+func parseJWK(data []byte) (Key, error) {
+	var encoded jwk
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("could not parse JWK: %w", err)
+	}
+
+	switch encoded.KeyType {
+	case "RSA":
+		return parseRSAJWK(encoded)
+	case "OKP":
+		return parseOKPJWK(encoded)
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type [%s]", encoded.KeyType)
+	}
+}
@@ -0,0 +1,219 @@
+package keyformat_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"util.tim/encrypto/adapters/asymetric/keyformat"
+)
+
+func generate(t *testing.T, keyType string) keyformat.Key {
+	bits := 0
+	if keyType == keyformat.TypeRSA {
+		bits = keyformat.MinRSABits
+	}
+
+	key, err := keyformat.Generate(keyType, bits)
+	if err != nil {
+		t.Log("Generate failed", keyType, err)
+		t.FailNow()
+	}
+
+	return key
+}
+
+// supported reports whether format can hold the key, which is what the
+// encoders refuse otherwise.
+func supported(keyType string, format string, private bool) bool {
+	switch format {
+	case keyformat.FormatPKCS1:
+		return keyType == keyformat.TypeRSA
+	case keyformat.FormatPKCS8:
+		return private
+	case keyformat.FormatOpenSSH:
+		return keyType != keyformat.TypeX25519
+	default:
+		return true
+	}
+}
+
+func Test_EveryTypeAndFormatRoundTrips(t *testing.T) {
+	for _, keyType := range keyformat.Types {
+		privateKey := generate(t, keyType)
+		expectedJWK, err := privateKey.Encode(keyformat.FormatJWK)
+		if err != nil {
+			t.Log("Encode failed", keyType, err)
+			t.FailNow()
+		}
+
+		for _, key := range []keyformat.Key{privateKey, privateKey.Public()} {
+			for _, format := range keyformat.Formats {
+				encoded, err := key.Encode(format)
+				if !supported(keyType, format, key.IsPrivate()) {
+					if err == nil {
+						t.Log("Expected encoding to fail", keyType, format, key.IsPrivate())
+						t.Fail()
+					}
+					continue
+				}
+				if err != nil {
+					t.Log("Encode failed", keyType, format, key.IsPrivate(), err)
+					t.Fail()
+					continue
+				}
+
+				parsed, err := keyformat.Parse(encoded)
+				if err != nil {
+					t.Log("Parse failed", keyType, format, key.IsPrivate(), err)
+					t.Fail()
+					continue
+				}
+
+				// pkix only ever holds the public half.
+				expectPrivate := key.IsPrivate() && format != keyformat.FormatPKIX
+				if parsed.Type() != keyType || parsed.IsPrivate() != expectPrivate || parsed.Fingerprint() != privateKey.Fingerprint() {
+					t.Log("Unexpected key", keyType, format, key.IsPrivate(), parsed.Type(), parsed.IsPrivate())
+					t.Fail()
+					continue
+				}
+
+				if expectPrivate {
+					actualJWK, err := parsed.Encode(keyformat.FormatJWK)
+					if err != nil || !bytes.Equal(actualJWK, expectedJWK) {
+						t.Log("The private key changed in a round trip", keyType, format, err)
+						t.Fail()
+					}
+				}
+			}
+		}
+	}
+}
+
+func Test_SSHFingerprintsOnlyForOpenSSHTypes(t *testing.T) {
+	for _, keyType := range keyformat.Types {
+		fingerprint := generate(t, keyType).SSHFingerprint()
+		if (fingerprint == "") != (keyType == keyformat.TypeX25519) {
+			t.Log("Unexpected SSH fingerprint", keyType, fingerprint)
+			t.Fail()
+		}
+	}
+}
+
+func Test_UnknownTypesAndFormatsAreRejected(t *testing.T) {
+	if _, err := keyformat.Generate("dsa", 0); err == nil {
+		t.Log("Expected an unknown type to be rejected")
+		t.Fail()
+	}
+
+	if _, err := keyformat.Generate(keyformat.TypeRSA, 1024); err == nil {
+		t.Log("Expected a short RSA key to be rejected")
+		t.Fail()
+	}
+
+	if _, err := keyformat.Generate(keyformat.TypeEd25519, 512); err == nil {
+		t.Log("Expected a bit size on an Ed25519 key to be rejected")
+		t.Fail()
+	}
+
+	if _, err := generate(t, keyformat.TypeEd25519).Encode("der"); err == nil {
+		t.Log("Expected an unknown format to be rejected")
+		t.Fail()
+	}
+
+	if _, err := keyformat.Parse([]byte("not a key")); err == nil {
+		t.Log("Expected garbage to be rejected")
+		t.Fail()
+	}
+}
+
+// jwkFields decodes the JWK of key so a test can tamper with its members.
+func jwkFields(t *testing.T, key keyformat.Key) map[string]string {
+	encoded, err := key.Encode(keyformat.FormatJWK)
+	if err != nil {
+		t.Log("Encode failed", err)
+		t.FailNow()
+	}
+
+	fields := map[string]string{}
+	if err = json.Unmarshal(encoded, &fields); err != nil {
+		t.Log("Unmarshal failed", err)
+		t.FailNow()
+	}
+
+	return fields
+}
+
+func Test_MalformedJWKsAreRejected(t *testing.T) {
+	short := base64.RawURLEncoding.EncodeToString(make([]byte, 31))
+	long := base64.RawURLEncoding.EncodeToString(make([]byte, 33))
+
+	for _, keyType := range []string{keyformat.TypeEd25519, keyformat.TypeX25519} {
+		other := jwkFields(t, generate(t, keyType))
+
+		cases := map[string]func(fields map[string]string){
+			"short x":      func(fields map[string]string) { fields["x"] = short },
+			"long x":       func(fields map[string]string) { fields["x"] = long },
+			"short d":      func(fields map[string]string) { fields["d"] = short },
+			"long d":       func(fields map[string]string) { fields["d"] = long },
+			"invalid d":    func(fields map[string]string) { fields["d"] = "not*base64" },
+			"padded x":     func(fields map[string]string) { fields["x"] += "=" },
+			"mismatched x": func(fields map[string]string) { fields["x"] = other["x"] },
+			"mismatched d": func(fields map[string]string) { fields["d"] = other["d"] },
+			"unknown crv":  func(fields map[string]string) { fields["crv"] = "P-256" },
+		}
+
+		for name, tamper := range cases {
+			fields := jwkFields(t, generate(t, keyType))
+			tamper(fields)
+
+			encoded, err := json.Marshal(fields)
+			if err != nil {
+				t.Log("Marshal failed", err)
+				t.FailNow()
+			}
+
+			if _, err = keyformat.Parse(encoded); err == nil {
+				t.Log("Expected the JWK to be rejected", keyType, name)
+				t.Fail()
+			}
+		}
+	}
+
+	fields := jwkFields(t, generate(t, keyformat.TypeRSA))
+	fields["q"] = fields["p"]
+	encoded, _ := json.Marshal(fields)
+	if _, err := keyformat.Parse(encoded); err == nil {
+		t.Log("Expected an inconsistent RSA JWK to be rejected")
+		t.Fail()
+	}
+}
+
+func Test_MalformedOpenSSHPrivateKeysAreRejected(t *testing.T) {
+	encoded, err := generate(t, keyformat.TypeEd25519).Encode(keyformat.FormatOpenSSH)
+	if err != nil {
+		t.Log("Encode failed", err)
+		t.FailNow()
+	}
+
+	block, _ := pem.Decode(encoded)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		t.Log("Expected an OpenSSH private key block")
+		t.FailNow()
+	}
+
+	malformed := [][]byte{
+		block.Bytes[:len(block.Bytes)/2],
+		[]byte("openssh-key-v1\x00garbage"),
+		[]byte("not-openssh-key\x00"),
+	}
+
+	for _, der := range malformed {
+		if _, err := keyformat.Parse(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})); err == nil {
+			t.Log("Expected a malformed OpenSSH private key to be rejected", len(der))
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package keyformat
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const openSSHMagic = "openssh-key-v1\x00"
+
+func sshPublicKey(publicKey interface{}) (ssh.PublicKey, error) {
+	if _, ok := publicKey.(x25519PublicKey); ok {
+		return nil, errors.New("OpenSSH has no format for X25519 keys")
+	}
+
+	return ssh.NewPublicKey(publicKey)
+}
+
+func fingerprintSSH(publicKey ssh.PublicKey) string {
+	return ssh.FingerprintSHA256(publicKey)
+}
+
+func encodeOpenSSHPublic(publicKey interface{}) ([]byte, error) {
+	sshKey, err := sshPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.MarshalAuthorizedKey(sshKey), nil
+}
+
+// encodeOpenSSHPrivate writes the unencrypted "openssh-key-v1" format from
+// PROTOCOL.key in the OpenSSH sources, which x/crypto/ssh can read but not
+// write.
+func encodeOpenSSHPrivate(privateKey interface{}) ([]byte, error) {
+	var publicKey ssh.PublicKey
+	var keyFields []byte
+	var err error
+
+	switch private := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if len(private.Primes) != 2 {
+			return nil, errors.New("multi-prime RSA keys can not be written in OpenSSH format")
+		}
+
+		if publicKey, err = ssh.NewPublicKey(&private.PublicKey); err != nil {
+			return nil, err
+		}
+
+		private.Precompute()
+		keyFields = ssh.Marshal(struct {
+			N       *big.Int
+			E       *big.Int
+			D       *big.Int
+			Iqmp    *big.Int
+			P       *big.Int
+			Q       *big.Int
+			Comment string
+		}{private.N, big.NewInt(int64(private.E)), private.D, private.Precomputed.Qinv, private.Primes[0], private.Primes[1], ""})
+	case ed25519.PrivateKey:
+		if publicKey, err = ssh.NewPublicKey(private.Public()); err != nil {
+			return nil, err
+		}
+
+		keyFields = ssh.Marshal(struct {
+			Public  []byte
+			Private []byte
+			Comment string
+		}{[]byte(private.Public().(ed25519.PublicKey)), []byte(private), ""})
+	default:
+		return nil, fmt.Errorf("OpenSSH has no format for [%T] keys", privateKey)
+	}
+
+	check := make([]byte, 4)
+	if _, err = io.ReadFull(rand.Reader, check); err != nil {
+		return nil, err
+	}
+
+	block := ssh.Marshal(struct {
+		Check1  uint32
+		Check2  uint32
+		KeyType string
+		Rest    []byte `ssh:"rest"`
+	}{binary.BigEndian.Uint32(check), binary.BigEndian.Uint32(check), publicKey.Type(), keyFields})
+
+	// The block is padded to the cipher block size, 8 for "none", with the
+	// bytes 1, 2, 3 and so on.
+	for i := byte(1); len(block)%8 != 0; i++ {
+		block = append(block, i)
+	}
+
+	encoded := append([]byte(openSSHMagic), ssh.Marshal(struct {
+		Cipher       string
+		KDF          string
+		KDFOptions   string
+		Count        uint32
+		PublicKey    []byte
+		PrivateBlock []byte
+	}{"none", "none", "", 1, publicKey.Marshal(), block})...)
+
+	return encodeBlock("OPENSSH PRIVATE KEY", encoded), nil
+}
+
+func parseOpenSSHPublic(data []byte) (Key, error) {
+	sshKey, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cryptoKey, ok := sshKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported OpenSSH key type [%s]", sshKey.Type())
+	}
+
+	return newPublicKey(cryptoKey.CryptoPublicKey())
+}
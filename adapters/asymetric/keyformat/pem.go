@@ -0,0 +1,165 @@
+package keyformat
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// oidX25519 is from RFC 8410, which crypto/x509 does not implement yet.
+var oidX25519 = asn1.ObjectIdentifier{1, 3, 101, 110}
+
+type pkcs8 struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+func encodeBlock(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func marshalPKIX(publicKey interface{}) ([]byte, error) {
+	if x25519Key, ok := publicKey.(x25519PublicKey); ok {
+		return asn1.Marshal(publicKeyInfo{
+			Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+			PublicKey: asn1.BitString{Bytes: x25519Key, BitLength: 8 * len(x25519Key)},
+		})
+	}
+
+	return x509.MarshalPKIXPublicKey(publicKey)
+}
+
+func encodePKIX(publicKey interface{}) ([]byte, error) {
+	der, err := marshalPKIX(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeBlock("PUBLIC KEY", der), nil
+}
+
+func encodePKCS8(privateKey interface{}) ([]byte, error) {
+	var der []byte
+	var err error
+	if x25519Key, ok := privateKey.(x25519PrivateKey); ok {
+		// The key is an OCTET STRING inside the PrivateKey OCTET STRING.
+		inner, err := asn1.Marshal([]byte(x25519Key))
+		if err != nil {
+			return nil, err
+		}
+
+		der, err = asn1.Marshal(pkcs8{
+			Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oidX25519},
+			PrivateKey: inner,
+		})
+	} else {
+		der, err = x509.MarshalPKCS8PrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeBlock("PRIVATE KEY", der), nil
+}
+
+func encodePKCS1(key *keyPair) ([]byte, error) {
+	if key.keyType != TypeRSA {
+		return nil, fmt.Errorf("PKCS1 holds RSA keys only, not [%s] keys", key.keyType)
+	}
+
+	if key.IsPrivate() {
+		return encodeBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key.privateKey.(*rsa.PrivateKey))), nil
+	}
+
+	return encodeBlock("RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(key.publicKey.(*rsa.PublicKey))), nil
+}
+
+func parsePKIX(der []byte) (Key, error) {
+	var info publicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err == nil && len(rest) == 0 && info.Algorithm.Algorithm.Equal(oidX25519) {
+		if len(info.PublicKey.Bytes) != 32 || info.PublicKey.BitLength != 256 {
+			return nil, errors.New("invalid X25519 public key")
+		}
+
+		return newPublicKey(x25519PublicKey(info.PublicKey.Bytes))
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPublicKey(publicKey)
+}
+
+func parsePKCS8(der []byte) (Key, error) {
+	var info pkcs8
+	if rest, err := asn1.Unmarshal(der, &info); err == nil && len(rest) == 0 && info.Algorithm.Algorithm.Equal(oidX25519) {
+		var privateKey []byte
+		if rest, err := asn1.Unmarshal(info.PrivateKey, &privateKey); err != nil || len(rest) != 0 || len(privateKey) != 32 {
+			return nil, errors.New("invalid X25519 private key")
+		}
+
+		return newKey(x25519PrivateKey(privateKey))
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(privateKey)
+}
+
+func parsePEM(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("could not find a PEM block")
+	}
+
+	if _, found := block.Headers["Proc-Type"]; found {
+		return nil, errors.New("encrypted PEM keys are not supported, remove the passphrase first")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return parsePKIX(block.Bytes)
+	case "RSA PUBLIC KEY":
+		publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return newPublicKey(publicKey)
+	case "PRIVATE KEY":
+		return parsePKCS8(block.Bytes)
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return newKey(privateKey)
+	case "OPENSSH PRIVATE KEY":
+		privateKey, err := ssh.ParseRawPrivateKey(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return newKey(privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block [%s]", block.Type)
+	}
+}
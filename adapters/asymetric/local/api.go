@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 
 	"util.tim/encrypto/core/asymetric"
 )
@@ -73,3 +74,39 @@ func NewRSAContainer() (asymetric.LocalRSAContainer, error) {
 		privateKey: privateKey,
 	}, nil
 }
+
+func parsePrivateKey(pemString string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("could not find a PEM block in the private key")
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err == nil {
+		return privateKey, nil
+	}
+
+	rawKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := rawKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("could not cast raw key to RSA Private Key")
+	}
+
+	return privateKey, nil
+}
+
+func NewRSAContainerFromPEM(pemString string) (asymetric.LocalRSAContainer, error) {
+	privateKey, err := parsePrivateKey(pemString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rsaContainer{
+		publicKey:  privateKey.PublicKey,
+		privateKey: privateKey,
+	}, nil
+}
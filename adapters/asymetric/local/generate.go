@@ -0,0 +1,75 @@
+package local
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+func encodePKCS8(privateKey interface{}) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func GenerateRSA(bits int) (string, error) {
+	if bits < 2048 {
+		return "", errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", err
+	}
+
+	return encodePKCS8(privateKey)
+}
+
+func GenerateEd25519() (string, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	return encodePKCS8(privateKey)
+}
+
+// PublicKeyPEM derives the PKIX public key for an RSA or Ed25519 private key.
+func PublicKeyPEM(pemString string) (string, error) {
+	signer, err := NewSignerFromPEM(pemString)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: signer.PublicKeyBytes()})), nil
+}
+
+// Ed25519Seed returns the 32 byte seed an Ed25519 private key is derived from.
+func Ed25519Seed(pemString string) ([]byte, error) {
+	privateKey, err := parseSigningKey(pemString)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("expected an Ed25519 private key")
+	}
+
+	return key.Seed(), nil
+}
+
+func Ed25519FromSeed(seed []byte) (string, error) {
+	if len(seed) != ed25519.SeedSize {
+		return "", fmt.Errorf("an Ed25519 seed is [%d] bytes, received [%d]", ed25519.SeedSize, len(seed))
+	}
+
+	return encodePKCS8(ed25519.NewKeyFromSeed(seed))
+}
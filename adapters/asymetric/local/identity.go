@@ -0,0 +1,58 @@
+package local
+
+import (
+	"encoding/json"
+
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/fileformat"
+)
+
+type unwrapHandler struct {
+	fileKey []byte
+	err     error
+}
+
+func (handler *unwrapHandler) Success(decrypted string) {
+	handler.fileKey = []byte(decrypted)
+}
+
+func (handler *unwrapHandler) Failure(err error) {
+	handler.err = err
+}
+
+type identity struct {
+	container   asymetric.LocalRSAContainer
+	fingerprint string
+}
+
+func (identity *identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != asymetric.OAEPStanzaType {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params asymetric.OAEPStanzaParams
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Fingerprint != identity.fingerprint {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	handler := &unwrapHandler{}
+	identity.container.Decrypt(handler, stanza.Body)
+
+	return handler.fileKey, handler.err
+}
+
+func NewIdentity(pemString string) (fileformat.Identity, error) {
+	container, err := NewRSAContainerFromPEM(pemString)
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity{
+		container:   container,
+		fingerprint: asymetric.Fingerprint(container.PublicKeyBytes()),
+	}, nil
+}
@@ -0,0 +1,96 @@
+package local
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"util.tim/encrypto/core/asymetric"
+)
+
+type rsaSigner struct {
+	privateKey     *rsa.PrivateKey
+	publicKeyBytes []byte
+}
+
+func (signer *rsaSigner) Algorithm() string {
+	return asymetric.SignatureRSAPSS
+}
+
+func (signer *rsaSigner) PublicKeyBytes() []byte {
+	return signer.publicKeyBytes
+}
+
+func (signer *rsaSigner) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+
+	return rsa.SignPSS(rand.Reader, signer.privateKey, crypto.SHA256, digest[:], &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+}
+
+type ed25519Signer struct {
+	privateKey     ed25519.PrivateKey
+	publicKeyBytes []byte
+}
+
+func (signer *ed25519Signer) Algorithm() string {
+	return asymetric.SignatureEd25519
+}
+
+func (signer *ed25519Signer) PublicKeyBytes() []byte {
+	return signer.publicKeyBytes
+}
+
+func (signer *ed25519Signer) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(signer.privateKey, message), nil
+}
+
+func parseSigningKey(pemString string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("could not find a PEM block in the private key")
+	}
+
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+
+	rawKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := rawKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot be used for signing")
+	}
+
+	return signer, nil
+}
+
+func NewSignerFromPEM(pemString string) (asymetric.Signer, error) {
+	privateKey, err := parseSigningKey(pemString)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return &rsaSigner{privateKey: key, publicKeyBytes: publicKeyBytes}, nil
+	case ed25519.PrivateKey:
+		return &ed25519Signer{privateKey: key, publicKeyBytes: publicKeyBytes}, nil
+	default:
+		return nil, errors.New("only RSA and Ed25519 private keys can sign")
+	}
+}
@@ -48,22 +48,27 @@ func attemptPKIX(pemBytes []byte) (*rsa.PublicKey, error) {
 	return publicKey, nil
 }
 
-func NewRSARemoteContainer(pemString string) (asymetric.RemoteRSAContainer, error) {
+func parsePublicKey(pemString string) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("could not find a PEM block in the public key")
+	}
 
 	publicKey, err := attemptPKCS1(block.Bytes)
 	if err == nil {
-		return &remoteContainer{
-			publicKey: publicKey,
-		}, nil
+		return publicKey, nil
 	}
 
-	publicKey, err = attemptPKIX(block.Bytes)
-	if err == nil {
-		return &remoteContainer{
-			publicKey: publicKey,
-		}, nil
+	return attemptPKIX(block.Bytes)
+}
+
+func NewRSARemoteContainer(pemString string) (asymetric.RemoteRSAContainer, error) {
+	publicKey, err := parsePublicKey(pemString)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, err
+	return &remoteContainer{
+		publicKey: publicKey,
+	}, nil
 }
@@ -0,0 +1,68 @@
+package remote
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/fileformat"
+)
+
+type recipient struct {
+	container   asymetric.RemoteRSAContainer
+	fingerprint string
+}
+
+func (recipient *recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	body, err := recipient.container.Encrypt(fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	params, err := json.Marshal(asymetric.OAEPStanzaParams{Fingerprint: recipient.fingerprint})
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{
+		Type:   asymetric.OAEPStanzaType,
+		Params: params,
+		Body:   body,
+	}, nil
+}
+
+func fingerprintOf(publicKey *rsa.PublicKey) (string, error) {
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	return asymetric.Fingerprint(publicKeyBytes), nil
+}
+
+func NewRecipient(pemString string) (fileformat.Recipient, error) {
+	publicKey, err := parsePublicKey(pemString)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint, err := fingerprintOf(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &recipient{
+		container:   &remoteContainer{publicKey: publicKey},
+		fingerprint: fingerprint,
+	}, nil
+}
+
+func Fingerprint(pemString string) (string, error) {
+	publicKey, err := parsePublicKey(pemString)
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprintOf(publicKey)
+}
@@ -0,0 +1,92 @@
+package remote
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"util.tim/encrypto/core/asymetric"
+)
+
+var errBadSignature = errors.New("signature is not valid")
+
+type rsaVerifier struct {
+	publicKey      *rsa.PublicKey
+	publicKeyBytes []byte
+}
+
+func (verifier *rsaVerifier) Algorithm() string {
+	return asymetric.SignatureRSAPSS
+}
+
+func (verifier *rsaVerifier) PublicKeyBytes() []byte {
+	return verifier.publicKeyBytes
+}
+
+func (verifier *rsaVerifier) Verify(message []byte, signature []byte) error {
+	digest := sha256.Sum256(message)
+
+	err := rsa.VerifyPSS(verifier.publicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+	if err != nil {
+		return errBadSignature
+	}
+
+	return nil
+}
+
+type ed25519Verifier struct {
+	publicKey      ed25519.PublicKey
+	publicKeyBytes []byte
+}
+
+func (verifier *ed25519Verifier) Algorithm() string {
+	return asymetric.SignatureEd25519
+}
+
+func (verifier *ed25519Verifier) PublicKeyBytes() []byte {
+	return verifier.publicKeyBytes
+}
+
+func (verifier *ed25519Verifier) Verify(message []byte, signature []byte) error {
+	if !ed25519.Verify(verifier.publicKey, message, signature) {
+		return errBadSignature
+	}
+
+	return nil
+}
+
+func NewVerifierFromPEM(pemString string) (asymetric.Verifier, error) {
+	block, _ := pem.Decode([]byte(pemString))
+	if block == nil {
+		return nil, errors.New("could not find a PEM block in the public key")
+	}
+
+	if publicKey, err := attemptPKCS1(block.Bytes); err == nil {
+		publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsaVerifier{publicKey: publicKey, publicKeyBytes: publicKeyBytes}, nil
+	}
+
+	rawKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := rawKey.(type) {
+	case *rsa.PublicKey:
+		return &rsaVerifier{publicKey: key, publicKeyBytes: block.Bytes}, nil
+	case ed25519.PublicKey:
+		return &ed25519Verifier{publicKey: key, publicKeyBytes: block.Bytes}, nil
+	default:
+		return nil, errors.New("only RSA and Ed25519 public keys can verify")
+	}
+}
@@ -0,0 +1,167 @@
+package sshkey
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"util.tim/encrypto/core/fileformat"
+)
+
+// An agent never hands out private keys and can only sign, so it can not
+// open the ssh-rsa and ssh-ed25519 stanzas. Instead the ssh-agent stanza
+// derives its wrapping key from the signature over a random salt, which only
+// works because Ed25519 and RSA PKCS #1 v1.5 signatures are deterministic.
+// The same agent has to be present to encrypt, and anyone who can use the
+// agent socket, such as a host it is forwarded to, can open these files.
+//
+// The challenge starts with text, so it can never be mistaken for the
+// session data an SSH server asks a key to sign.
+const agentChallenge = "encrypto ssh-agent challenge\x00"
+
+func agentKEK(signer agent.ExtendedAgent, publicKey ssh.PublicKey, salt []byte) ([]byte, error) {
+	challenge := append([]byte(agentChallenge), salt...)
+
+	var flags agent.SignatureFlags
+	if publicKey.Type() == ssh.KeyAlgoRSA {
+		flags = agent.SignatureFlagRsaSha256
+	}
+
+	signature, err := signer.SignWithFlags(publicKey, challenge, flags)
+	if err != nil {
+		return nil, fmt.Errorf("ssh-agent could not sign with [%s]: %w", ssh.FingerprintSHA256(publicKey), err)
+	}
+
+	kek := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, signature.Blob, salt, []byte("encrypto ssh-agent")), kek); err != nil {
+		return nil, err
+	}
+
+	return kek, nil
+}
+
+type agentRecipient struct {
+	agent     agent.ExtendedAgent
+	publicKey ssh.PublicKey
+}
+
+// NewAgentRecipients returns a recipient for every ssh-rsa and ssh-ed25519
+// key in the agent, after checking that each one signs deterministically.
+func NewAgentRecipients(signer agent.ExtendedAgent) ([]fileformat.Recipient, error) {
+	keys, err := signer.List()
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := []fileformat.Recipient{}
+	for _, agentKey := range keys {
+		key, err := ssh.ParsePublicKey(agentKey.Marshal())
+		if err != nil || checkPublicKey(key) != nil {
+			continue
+		}
+
+		salt := make([]byte, 32)
+		if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, err
+		}
+
+		first, err := agentKEK(signer, key, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		second, err := agentKEK(signer, key, salt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !bytes.Equal(first, second) {
+			return nil, fmt.Errorf("the agent key [%s] does not sign deterministically and can not protect files", ssh.FingerprintSHA256(key))
+		}
+
+		recipients = append(recipients, &agentRecipient{agent: signer, publicKey: key})
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("ssh-agent holds no ssh-rsa or ssh-ed25519 keys")
+	}
+
+	return recipients, nil
+}
+
+func (recipient *agentRecipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	kek, err := agentKEK(recipient.agent, recipient.publicKey, salt)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	body, err := fileformat.WrapKey(kek, fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	params, err := json.Marshal(Params{Fingerprint: ssh.FingerprintSHA256(recipient.publicKey), Salt: salt})
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{Type: StanzaAgent, Params: params, Body: body}, nil
+}
+
+type agentIdentity struct {
+	agent agent.ExtendedAgent
+	keys  map[string]ssh.PublicKey
+}
+
+func NewAgentIdentity(signer agent.ExtendedAgent) (fileformat.Identity, error) {
+	keys, err := signer.List()
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &agentIdentity{agent: signer, keys: map[string]ssh.PublicKey{}}
+	for _, key := range keys {
+		identity.keys[ssh.FingerprintSHA256(key)] = key
+	}
+
+	return identity, nil
+}
+
+func (identity *agentIdentity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaAgent && stanza.Type != StanzaRSA && stanza.Type != StanzaEd25519 {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	publicKey, found := identity.keys[params.Fingerprint]
+	if !found {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	if stanza.Type != StanzaAgent {
+		return nil, fmt.Errorf("%w: the key [%s] is in ssh-agent, but an agent can only open files encrypted with -ssh-agent, use the private key file instead", fileformat.ErrIncorrectIdentity, params.Fingerprint)
+	}
+
+	kek, err := agentKEK(identity.agent, publicKey, params.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileformat.UnwrapKey(kek, stanza.Body)
+}
@@ -0,0 +1,146 @@
+package sshkey
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	StanzaRSA     = "ssh-rsa"
+	StanzaEd25519 = "ssh-ed25519"
+	StanzaAgent   = "ssh-agent"
+
+	minRSABits = 2048
+)
+
+var ErrPassphraseRequired = errors.New("the SSH private key is protected by a passphrase")
+
+// Params names the SSH key a stanza was written for by the fingerprint
+// ssh-keygen -l prints.
+type Params struct {
+	Fingerprint string `json:"fingerprint"`
+	Ephemeral   []byte `json:"ephemeral,omitempty"`
+	Salt        []byte `json:"salt,omitempty"`
+}
+
+func checkPublicKey(publicKey ssh.PublicKey) error {
+	cryptoKey, ok := publicKey.(ssh.CryptoPublicKey)
+	if !ok {
+		return fmt.Errorf("unsupported SSH key type [%s], expected ssh-rsa or ssh-ed25519", publicKey.Type())
+	}
+
+	switch key := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSABits {
+			return fmt.Errorf("ssh-rsa keys must be at least [%d] bits, received [%d]", minRSABits, key.N.BitLen())
+		}
+
+		return nil
+	case ed25519.PublicKey:
+		return nil
+	default:
+		return fmt.Errorf("unsupported SSH key type [%s], expected ssh-rsa or ssh-ed25519", publicKey.Type())
+	}
+}
+
+func newRecipient(publicKey ssh.PublicKey) (fileformat.Recipient, error) {
+	if err := checkPublicKey(publicKey); err != nil {
+		return nil, err
+	}
+
+	switch key := publicKey.(ssh.CryptoPublicKey).CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return &rsaRecipient{publicKey: key, fingerprint: ssh.FingerprintSHA256(publicKey)}, nil
+	default:
+		return newEd25519Recipient(publicKey, key.(ed25519.PublicKey))
+	}
+}
+
+// NewRecipient reads a single public key in authorized_keys format.
+func NewRecipient(authorizedKey []byte) (fileformat.Recipient, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey(authorizedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRecipient(publicKey)
+}
+
+// ParseRecipients reads every key of an authorized_keys file, skipping blank
+// lines and comments, so a whole team file can be used at once.
+func ParseRecipients(authorizedKeys []byte) ([]fileformat.Recipient, error) {
+	recipients := []fileformat.Recipient{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(authorizedKeys))
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		recipient, err := NewRecipient(text)
+		if err != nil {
+			return nil, fmt.Errorf("line [%d]: %w", line, err)
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.New("no SSH public keys found")
+	}
+
+	return recipients, nil
+}
+
+func newIdentity(rawKey interface{}) (fileformat.Identity, error) {
+	switch key := rawKey.(type) {
+	case *rsa.PrivateKey:
+		publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsaIdentity{privateKey: key, fingerprint: ssh.FingerprintSHA256(publicKey)}, nil
+	case *ed25519.PrivateKey:
+		return newEd25519Identity(*key)
+	case ed25519.PrivateKey:
+		return newEd25519Identity(key)
+	default:
+		return nil, fmt.Errorf("unsupported SSH private key [%T], expected RSA or Ed25519", rawKey)
+	}
+}
+
+// NewIdentity reads an OpenSSH or PEM private key file.
+func NewIdentity(privateKey []byte) (fileformat.Identity, error) {
+	rawKey, err := ssh.ParseRawPrivateKey(privateKey)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, ErrPassphraseRequired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return newIdentity(rawKey)
+}
+
+func NewIdentityWithPassphrase(privateKey []byte, passphrase []byte) (fileformat.Identity, error) {
+	rawKey, err := ssh.ParseRawPrivateKeyWithPassphrase(privateKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return newIdentity(rawKey)
+}
@@ -0,0 +1,175 @@
+package sshkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/ssh"
+	"util.tim/encrypto/core/fileformat"
+)
+
+var fieldPrime = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+func reverse(data []byte) []byte {
+	reversed := make([]byte, len(data))
+	for i, b := range data {
+		reversed[len(data)-1-i] = b
+	}
+
+	return reversed
+}
+
+// montgomeryPublicKey maps an Ed25519 public key to the X25519 public key of
+// the same secret with u = (1 + y) / (1 - y), as RFC 7748 section 4.1
+// describes. Only the y coordinate is needed, so the sign bit is dropped.
+func montgomeryPublicKey(publicKey ed25519.PublicKey) ([]byte, error) {
+	encoded := reverse(publicKey)
+	encoded[0] &= 0x7f
+	y := new(big.Int).SetBytes(encoded)
+	if y.Cmp(fieldPrime) >= 0 {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+
+	denominator := new(big.Int).Sub(big.NewInt(1), y)
+	denominator.Mod(denominator, fieldPrime)
+	if denominator.Sign() == 0 {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+
+	u := new(big.Int).Add(big.NewInt(1), y)
+	u.Mul(u, new(big.Int).ModInverse(denominator, fieldPrime))
+	u.Mod(u, fieldPrime)
+
+	padded := make([]byte, curve25519.PointSize)
+	u.FillBytes(padded)
+
+	return reverse(padded), nil
+}
+
+// montgomeryPrivateKey is the scalar Ed25519 derives from the seed, which
+// X25519 clamps the same way.
+func montgomeryPrivateKey(privateKey ed25519.PrivateKey) []byte {
+	digest := sha512.Sum512(privateKey.Seed())
+
+	return digest[:curve25519.ScalarSize]
+}
+
+// ed25519KEK binds the wrapping key to the ephemeral share and to the SSH
+// key itself, not just its X25519 form.
+func ed25519KEK(shared []byte, ephemeral []byte, sshKey []byte) ([]byte, error) {
+	salt := append(append([]byte{}, ephemeral...), sshKey...)
+
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("encrypto ssh-ed25519")), kek); err != nil {
+		return nil, err
+	}
+
+	return kek, nil
+}
+
+type ed25519Recipient struct {
+	sshKey      []byte
+	montgomery  []byte
+	fingerprint string
+}
+
+func newEd25519Recipient(publicKey ssh.PublicKey, key ed25519.PublicKey) (fileformat.Recipient, error) {
+	montgomery, err := montgomeryPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ed25519Recipient{
+		sshKey:      publicKey.Marshal(),
+		montgomery:  montgomery,
+		fingerprint: ssh.FingerprintSHA256(publicKey),
+	}, nil
+}
+
+func (recipient *ed25519Recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	scalar := make([]byte, curve25519.ScalarSize)
+	if _, err := io.ReadFull(rand.Reader, scalar); err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	ephemeral, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	shared, err := curve25519.X25519(scalar, recipient.montgomery)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	kek, err := ed25519KEK(shared, ephemeral, recipient.sshKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	body, err := fileformat.WrapKey(kek, fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	params, err := json.Marshal(Params{Fingerprint: recipient.fingerprint, Ephemeral: ephemeral})
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{Type: StanzaEd25519, Params: params, Body: body}, nil
+}
+
+type ed25519Identity struct {
+	scalar      []byte
+	sshKey      []byte
+	fingerprint string
+}
+
+func newEd25519Identity(privateKey ed25519.PrivateKey) (fileformat.Identity, error) {
+	publicKey, err := ssh.NewPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ed25519Identity{
+		scalar:      montgomeryPrivateKey(privateKey),
+		sshKey:      publicKey.Marshal(),
+		fingerprint: ssh.FingerprintSHA256(publicKey),
+	}, nil
+}
+
+func (identity *ed25519Identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaEd25519 {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Fingerprint != identity.fingerprint {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	shared, err := curve25519.X25519(identity.scalar, params.Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := ed25519KEK(shared, params.Ephemeral, identity.sshKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileformat.UnwrapKey(kek, stanza.Body)
+}
@@ -0,0 +1,8 @@
+package sshkey
+
+// The Ed25519 to X25519 conversion is checked against a known vector, which
+// needs the unexported halves.
+var (
+	MontgomeryPublicKey  = montgomeryPublicKey
+	MontgomeryPrivateKey = montgomeryPrivateKey
+)
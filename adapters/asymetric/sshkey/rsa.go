@@ -0,0 +1,53 @@
+package sshkey
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+var rsaLabel = []byte("encrypto ssh-rsa")
+
+type rsaRecipient struct {
+	publicKey   *rsa.PublicKey
+	fingerprint string
+}
+
+func (recipient *rsaRecipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	body, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, recipient.publicKey, fileKey, rsaLabel)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	params, err := json.Marshal(Params{Fingerprint: recipient.fingerprint})
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{Type: StanzaRSA, Params: params, Body: body}, nil
+}
+
+type rsaIdentity struct {
+	privateKey  *rsa.PrivateKey
+	fingerprint string
+}
+
+func (identity *rsaIdentity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaRSA {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Fingerprint != identity.fingerprint {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	return rsa.DecryptOAEP(sha256.New(), nil, identity.privateKey, stanza.Body, rsaLabel)
+}
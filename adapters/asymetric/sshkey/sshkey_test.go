@@ -0,0 +1,233 @@
+package sshkey_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"util.tim/encrypto/adapters/asymetric/sshkey"
+	"util.tim/encrypto/core/fileformat"
+)
+
+var fileKey = bytes.Repeat([]byte{42}, fileformat.FileKeySize)
+
+func decodeHex(t *testing.T, value string) []byte {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Log("DecodeString failed", err)
+		t.FailNow()
+	}
+
+	return decoded
+}
+
+func Test_Ed25519ConvertsToX25519(t *testing.T) {
+	// The first key of RFC 8032 section 7.1, and the X25519 public key
+	// libsodium's crypto_sign_ed25519_pk_to_curve25519 derives from it.
+	privateKey := ed25519.NewKeyFromSeed(decodeHex(t, "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"))
+	expected := decodeHex(t, "d85e07ec22b0ad881537c2f44d662d1a143cf830c57aca4305d85c7a90f6b62e")
+
+	publicKey, err := sshkey.MontgomeryPublicKey(privateKey.Public().(ed25519.PublicKey))
+	if err != nil || !bytes.Equal(publicKey, expected) {
+		t.Log("Unexpected X25519 public key", hex.EncodeToString(publicKey), err)
+		t.Fail()
+	}
+
+	derived, err := curve25519.X25519(sshkey.MontgomeryPrivateKey(privateKey), curve25519.Basepoint)
+	if err != nil || !bytes.Equal(derived, expected) {
+		t.Log("The X25519 private key does not match the public key", hex.EncodeToString(derived), err)
+		t.Fail()
+	}
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Log("GenerateKey failed", err)
+		t.FailNow()
+	}
+
+	return privateKey
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Log("GenerateKey failed", err)
+		t.FailNow()
+	}
+
+	return privateKey
+}
+
+func newRecipient(t *testing.T, publicKey interface{}) fileformat.Recipient {
+	sshKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Log("NewPublicKey failed", err)
+		t.FailNow()
+	}
+
+	recipient, err := sshkey.NewRecipient(ssh.MarshalAuthorizedKey(sshKey))
+	if err != nil {
+		t.Log("NewRecipient failed", err)
+		t.FailNow()
+	}
+
+	return recipient
+}
+
+func newIdentity(t *testing.T, privateKey interface{}) fileformat.Identity {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Log("MarshalPKCS8PrivateKey failed", err)
+		t.FailNow()
+	}
+
+	identity, err := sshkey.NewIdentity(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Log("NewIdentity failed", err)
+		t.FailNow()
+	}
+
+	return identity
+}
+
+func wrap(t *testing.T, recipient fileformat.Recipient) fileformat.Stanza {
+	stanza, err := recipient.Wrap(fileKey)
+	if err != nil {
+		t.Log("Wrap failed", err)
+		t.FailNow()
+	}
+
+	return stanza
+}
+
+func Test_KeysRoundTrip(t *testing.T) {
+	ed25519Key, rsaKey := newEd25519Key(t), newRSAKey(t)
+
+	cases := []struct {
+		stanzaType string
+		recipient  fileformat.Recipient
+		identity   fileformat.Identity
+	}{
+		{sshkey.StanzaEd25519, newRecipient(t, ed25519Key.Public()), newIdentity(t, ed25519Key)},
+		{sshkey.StanzaRSA, newRecipient(t, &rsaKey.PublicKey), newIdentity(t, rsaKey)},
+	}
+
+	for _, c := range cases {
+		stanza := wrap(t, c.recipient)
+		if stanza.Type != c.stanzaType {
+			t.Log("Unexpected stanza type", stanza.Type, "expected", c.stanzaType)
+			t.Fail()
+		}
+
+		unwrapped, err := c.identity.Unwrap(stanza)
+		if err != nil || !bytes.Equal(unwrapped, fileKey) {
+			t.Log("Unwrap failed", c.stanzaType, err)
+			t.Fail()
+		}
+	}
+}
+
+func Test_WrongKeysAreIncorrectIdentities(t *testing.T) {
+	ed25519Key, otherEd25519Key := newEd25519Key(t), newEd25519Key(t)
+	rsaKey, otherRSAKey := newRSAKey(t), newRSAKey(t)
+
+	ed25519Stanza := wrap(t, newRecipient(t, ed25519Key.Public()))
+	rsaStanza := wrap(t, newRecipient(t, &rsaKey.PublicKey))
+
+	cases := []struct {
+		name     string
+		stanza   fileformat.Stanza
+		identity fileformat.Identity
+	}{
+		{"other ed25519 key", ed25519Stanza, newIdentity(t, otherEd25519Key)},
+		{"other rsa key", rsaStanza, newIdentity(t, otherRSAKey)},
+		{"rsa key for ed25519", ed25519Stanza, newIdentity(t, rsaKey)},
+		{"ed25519 key for rsa", rsaStanza, newIdentity(t, ed25519Key)},
+	}
+
+	for _, c := range cases {
+		if _, err := c.identity.Unwrap(c.stanza); !errors.Is(err, fileformat.ErrIncorrectIdentity) {
+			t.Log("Expected ErrIncorrectIdentity", c.name, "received", err)
+			t.Fail()
+		}
+	}
+}
+
+func newAgent(t *testing.T, privateKeys ...interface{}) agent.ExtendedAgent {
+	keyring := agent.NewKeyring().(agent.ExtendedAgent)
+	for _, privateKey := range privateKeys {
+		if err := keyring.Add(agent.AddedKey{PrivateKey: privateKey}); err != nil {
+			t.Log("Add failed", err)
+			t.FailNow()
+		}
+	}
+
+	return keyring
+}
+
+func Test_AgentKeysRoundTrip(t *testing.T) {
+	ed25519Key, rsaKey := newEd25519Key(t), newRSAKey(t)
+	signer := newAgent(t, ed25519Key, rsaKey)
+
+	recipients, err := sshkey.NewAgentRecipients(signer)
+	if err != nil || len(recipients) != 2 {
+		t.Log("Expected a recipient per agent key", len(recipients), err)
+		t.FailNow()
+	}
+
+	identity, err := sshkey.NewAgentIdentity(signer)
+	if err != nil {
+		t.Log("NewAgentIdentity failed", err)
+		t.FailNow()
+	}
+
+	other, err := sshkey.NewAgentIdentity(newAgent(t, newEd25519Key(t)))
+	if err != nil {
+		t.Log("NewAgentIdentity failed", err)
+		t.FailNow()
+	}
+
+	for _, recipient := range recipients {
+		stanza := wrap(t, recipient)
+		if stanza.Type != sshkey.StanzaAgent {
+			t.Log("Unexpected stanza type", stanza.Type)
+			t.Fail()
+		}
+
+		unwrapped, err := identity.Unwrap(stanza)
+		if err != nil || !bytes.Equal(unwrapped, fileKey) {
+			t.Log("Unwrap failed", err)
+			t.Fail()
+		}
+
+		if _, err = other.Unwrap(stanza); !errors.Is(err, fileformat.ErrIncorrectIdentity) {
+			t.Log("Expected another agent to be an incorrect identity, received", err)
+			t.Fail()
+		}
+	}
+
+	// An agent can not open stanzas written for the key file, even when it
+	// holds the key.
+	if _, err = identity.Unwrap(wrap(t, newRecipient(t, ed25519Key.Public()))); !errors.Is(err, fileformat.ErrIncorrectIdentity) {
+		t.Log("Expected ErrIncorrectIdentity for an ssh-ed25519 stanza, received", err)
+		t.Fail()
+	}
+}
+
+func Test_EmptyAgentsHaveNoRecipients(t *testing.T) {
+	if _, err := sshkey.NewAgentRecipients(newAgent(t)); err == nil {
+		t.Log("Expected an empty agent to be rejected")
+		t.Fail()
+	}
+}
@@ -0,0 +1,394 @@
+package pgp
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	openpgpErrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	MessageBlock   = "PGP MESSAGE"
+	SignatureBlock = "PGP SIGNATURE"
+
+	maxPassphraseAttempts = 3
+)
+
+// ErrUnknownSigner means the message is signed by a key that is not in the
+// keyring, so the signature could not be checked.
+var ErrUnknownSigner = errors.New("the signing key is not in the keyring")
+
+// Prompt returns the passphrase that unlocks what description names.
+type Prompt func(description string) ([]byte, error)
+
+type EncryptOptions struct {
+	// Recipients are fingerprints, key IDs or parts of user IDs. Without
+	// any, the message is encrypted to Passphrase instead.
+	Recipients []string
+	Passphrase []byte
+	// Signer optionally names a secret key to sign the message with.
+	Signer   string
+	Armor    bool
+	FileName string
+	ModTime  time.Time
+}
+
+type Message struct {
+	io.Reader
+	Encrypted bool
+	FileName  string
+	ModTime   time.Time
+	details   *openpgp.MessageDetails
+}
+
+// Signer describes the key that signed the message, once the plaintext has
+// been read to the end, and is "" for unsigned messages. Reading reaches the
+// end without an error even when the signature does not match, so only
+// Signer reports it, and then the integrity check of an encrypted message
+// may not have run either: the plaintext must be discarded.
+func (message *Message) Signer() (string, error) {
+	if !message.details.IsSigned {
+		return "", nil
+	}
+
+	if message.details.SignedBy == nil {
+		return "", fmt.Errorf("%w [%016X]", ErrUnknownSigner, message.details.SignedByKeyId)
+	}
+
+	if message.details.SignatureError != nil {
+		return "", fmt.Errorf("bad OpenPGP signature from [%s]: %w", describe(message.details.SignedBy.Entity), message.details.SignatureError)
+	}
+
+	return describe(message.details.SignedBy.Entity), nil
+}
+
+type Keyring interface {
+	Encrypt(dst io.Writer, options EncryptOptions, prompt Prompt) (io.WriteCloser, error)
+	Decrypt(src io.Reader, prompt Prompt) (*Message, error)
+	// Sign writes an armored detached signature.
+	Sign(dst io.Writer, src io.Reader, signer string, prompt Prompt) error
+	// Verify checks an armored or binary detached signature and describes
+	// the key that made it.
+	Verify(signed io.Reader, signature io.Reader) (string, error)
+}
+
+type keyring struct {
+	entities openpgp.EntityList
+}
+
+var config = &packet.Config{
+	DefaultCipher:          packet.CipherAES256,
+	DefaultHash:            crypto.SHA256,
+	DefaultCompressionAlgo: packet.CompressionNone,
+}
+
+// ReadKeyring merges armored or binary keyrings, as gpg --export and
+// --export-secret-keys write them, including the Ed25519 and Curve25519 keys
+// current gpg versions generate by default.
+func ReadKeyring(sources ...[]byte) (Keyring, error) {
+	entities := openpgp.EntityList{}
+
+	for _, source := range sources {
+		var read openpgp.EntityList
+		var err error
+		if bytes.HasPrefix(bytes.TrimSpace(source), []byte("-----BEGIN PGP")) {
+			read, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(source))
+		} else {
+			read, err = openpgp.ReadKeyRing(bytes.NewReader(source))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read keyring: %w", err)
+		}
+
+		entities = append(entities, read...)
+	}
+
+	return &keyring{entities: entities}, nil
+}
+
+// IsMessage tells an OpenPGP message from an encrypto file by its first
+// bytes: an armor line, or a packet tag, which always has the high bit set.
+func IsMessage(prefix []byte) bool {
+	trimmed := bytes.TrimLeft(prefix, " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("-----BEGIN "+MessageBlock+"-----")) {
+		return true
+	}
+
+	return len(prefix) > 0 && prefix[0]&0x80 != 0
+}
+
+func describe(entity *openpgp.Entity) string {
+	description := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+	for name := range entity.Identities {
+		return description + " " + name
+	}
+
+	return description
+}
+
+func normalizeQuery(query string) string {
+	query = strings.TrimPrefix(strings.TrimPrefix(query, "0x"), "0X")
+
+	return strings.ToUpper(strings.Replace(query, " ", "", -1))
+}
+
+func isHex(value string) bool {
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+func (keyring *keyring) matches(entity *openpgp.Entity, query string) bool {
+	normalized := normalizeQuery(query)
+	if isHex(normalized) && (len(normalized) == 40 || len(normalized) == 16) {
+		keys := []*packet.PublicKey{entity.PrimaryKey}
+		for _, subkey := range entity.Subkeys {
+			keys = append(keys, subkey.PublicKey)
+		}
+
+		for _, key := range keys {
+			if fmt.Sprintf("%X", key.Fingerprint) == normalized || fmt.Sprintf("%016X", key.KeyId) == normalized {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for name := range entity.Identities {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(query)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the single entity query names, refusing to guess between
+// several.
+func (keyring *keyring) find(query string, secret bool) (*openpgp.Entity, error) {
+	found := []*openpgp.Entity{}
+	for _, entity := range keyring.entities {
+		if keyring.matches(entity, query) && (!secret || entity.PrivateKey != nil) {
+			found = append(found, entity)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		kind := "key"
+		if secret {
+			kind = "secret key"
+		}
+
+		return nil, fmt.Errorf("no %s in the keyring matches [%s]", kind, query)
+	case 1:
+		return found[0], nil
+	default:
+		return nil, fmt.Errorf("[%s] matches [%d] keys, use a fingerprint instead", query, len(found))
+	}
+}
+
+// unlock decrypts every secret key of entity with one passphrase, the way
+// gpg protects them.
+func unlock(entity *openpgp.Entity, prompt Prompt) error {
+	keys := []*packet.PrivateKey{}
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		keys = append(keys, entity.PrivateKey)
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			keys = append(keys, subkey.PrivateKey)
+		}
+	}
+
+	for attempt := 0; len(keys) > 0; attempt++ {
+		if attempt == maxPassphraseAttempts {
+			return fmt.Errorf("could not unlock [%s]", describe(entity))
+		}
+
+		passphrase, err := prompt("PGP key " + describe(entity))
+		if err != nil {
+			return err
+		}
+
+		if keys[0].Decrypt(passphrase) != nil {
+			continue
+		}
+
+		for _, key := range keys[1:] {
+			if err = key.Decrypt(passphrase); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+type armoredWriter struct {
+	io.WriteCloser
+	armor io.WriteCloser
+}
+
+func (writer *armoredWriter) Close() error {
+	if err := writer.WriteCloser.Close(); err != nil {
+		return err
+	}
+
+	return writer.armor.Close()
+}
+
+func (keyring *keyring) Encrypt(dst io.Writer, options EncryptOptions, prompt Prompt) (io.WriteCloser, error) {
+	if len(options.Recipients) == 0 && len(options.Passphrase) == 0 {
+		return nil, errors.New("at least one recipient or a passphrase is required")
+	}
+
+	if len(options.Recipients) > 0 && len(options.Passphrase) > 0 {
+		return nil, errors.New("OpenPGP messages are encrypted either to keys or to a passphrase, not both")
+	}
+
+	recipients := []*openpgp.Entity{}
+	for _, query := range options.Recipients {
+		entity, err := keyring.find(query, false)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, entity)
+	}
+
+	var signer *openpgp.Entity
+	if options.Signer != "" {
+		var err error
+		if signer, err = keyring.find(options.Signer, true); err != nil {
+			return nil, err
+		}
+
+		if err = unlock(signer, prompt); err != nil {
+			return nil, err
+		}
+	}
+
+	if signer != nil && len(recipients) == 0 {
+		return nil, errors.New("a passphrase encrypted OpenPGP message can not be signed, sign it separately")
+	}
+
+	output := dst
+	var armored io.WriteCloser
+	if options.Armor {
+		var err error
+		if armored, err = armor.Encode(dst, MessageBlock, nil); err != nil {
+			return nil, err
+		}
+		output = armored
+	}
+
+	hints := &openpgp.FileHints{IsBinary: true, FileName: options.FileName, ModTime: options.ModTime}
+
+	var plaintext io.WriteCloser
+	var err error
+	if len(recipients) > 0 {
+		plaintext, err = openpgp.Encrypt(output, recipients, signer, hints, config)
+	} else {
+		plaintext, err = openpgp.SymmetricallyEncrypt(output, options.Passphrase, hints, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if armored != nil {
+		return &armoredWriter{WriteCloser: plaintext, armor: armored}, nil
+	}
+
+	return plaintext, nil
+}
+
+func (keyring *keyring) Decrypt(src io.Reader, prompt Prompt) (*Message, error) {
+	packets, err := dearmor(src, MessageBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	packets, err = requireIntegrity(packets)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := 0
+	details, err := openpgp.ReadMessage(packets, keyring.entities, func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		attempts++
+		if attempts > maxPassphraseAttempts {
+			return nil, errors.New("no passphrase opened the message")
+		}
+
+		for _, key := range keys {
+			passphrase, err := prompt("PGP key " + describe(key.Entity))
+			if err != nil {
+				return nil, err
+			}
+
+			if key.PrivateKey.Decrypt(passphrase) == nil {
+				// ReadMessage tries the unlocked key on its next pass.
+				return nil, nil
+			}
+		}
+
+		if symmetric {
+			return prompt("the message")
+		}
+
+		return nil, nil
+	}, config)
+	if err == openpgpErrors.ErrKeyIncorrect {
+		return nil, errors.New("no secret key in the keyring can decrypt the message")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Reader:    details.UnverifiedBody,
+		Encrypted: details.IsEncrypted,
+		FileName:  details.LiteralData.FileName,
+		ModTime:   time.Unix(int64(details.LiteralData.Time), 0),
+		details:   details,
+	}, nil
+}
+
+func (keyring *keyring) Sign(dst io.Writer, src io.Reader, signer string, prompt Prompt) error {
+	entity, err := keyring.find(signer, true)
+	if err != nil {
+		return err
+	}
+
+	if err = unlock(entity, prompt); err != nil {
+		return err
+	}
+
+	return openpgp.ArmoredDetachSign(dst, entity, src, config)
+}
+
+func (keyring *keyring) Verify(signed io.Reader, signature io.Reader) (string, error) {
+	packets, err := dearmor(signature, SignatureBlock)
+	if err != nil {
+		return "", err
+	}
+
+	entity, err := openpgp.CheckDetachedSignature(keyring.entities, signed, packets, config)
+	if err != nil {
+		return "", err
+	}
+
+	return describe(entity), nil
+}
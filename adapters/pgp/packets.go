@@ -0,0 +1,144 @@
+package pgp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+const (
+	tagPublicKeyEncryptedKey  = 1
+	tagSymmetricKeyEncrypted  = 3
+	tagSymmetricallyEncrypted = 9
+	tagMarker                 = 10
+)
+
+// dearmor returns the packets of src, decoding the armor around them when
+// there is one.
+func dearmor(src io.Reader, blockType string) (io.Reader, error) {
+	buffered := bufio.NewReader(src)
+
+	prefix, _ := buffered.Peek(64)
+	if !bytes.HasPrefix(bytes.TrimLeft(prefix, " \t\r\n"), []byte("-----BEGIN ")) {
+		return buffered, nil
+	}
+
+	block, err := armor.Decode(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("could not read armor: %w", err)
+	}
+
+	if block.Type != blockType {
+		return nil, fmt.Errorf("expected a [%s] block, received [%s]", blockType, block.Type)
+	}
+
+	return block.Body, nil
+}
+
+// packetHeader reads a packet header, old or new format, and returns the
+// tag and the length of the body. Partial and indeterminate lengths are
+// refused, since only the data packets after the session keys use them.
+func packetHeader(src *bufio.Reader, header *bytes.Buffer) (int, int, error) {
+	first, err := src.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	header.WriteByte(first)
+
+	if first&0x80 == 0 {
+		return 0, 0, errors.New("not an OpenPGP packet")
+	}
+
+	readLength := func(size int) (int, error) {
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(src, buf); err != nil {
+			return 0, err
+		}
+		header.Write(buf)
+
+		switch size {
+		case 1:
+			return int(buf[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(buf)), nil
+		default:
+			return int(binary.BigEndian.Uint32(buf)), nil
+		}
+	}
+
+	if first&0x40 == 0 {
+		tag := int(first>>2) & 0x0f
+		switch first & 0x03 {
+		case 0:
+			length, err := readLength(1)
+			return tag, length, err
+		case 1:
+			length, err := readLength(2)
+			return tag, length, err
+		case 2:
+			length, err := readLength(4)
+			return tag, length, err
+		default:
+			return tag, 0, fmt.Errorf("packet [%d] has an indeterminate length", tag)
+		}
+	}
+
+	tag := int(first & 0x3f)
+	octet, err := readLength(1)
+	if err != nil {
+		return tag, 0, err
+	}
+
+	switch {
+	case octet < 192:
+		return tag, octet, nil
+	case octet < 224:
+		second, err := readLength(1)
+		return tag, (octet-192)<<8 + second + 192, err
+	case octet == 255:
+		length, err := readLength(4)
+		return tag, length, err
+	default:
+		return tag, 0, fmt.Errorf("packet [%d] has a partial length", tag)
+	}
+}
+
+// requireIntegrity refuses messages encrypted without a modification
+// detection code, which ReadMessage would otherwise decrypt: anyone can flip
+// bits in their plaintext unnoticed. It reads past the session key packets
+// to the first data packet and hands back a reader for the whole message.
+func requireIntegrity(src io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(src)
+	consumed := &bytes.Buffer{}
+
+	for {
+		first, err := buffered.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the OpenPGP message: %w", err)
+		}
+
+		tag := int(first[0] & 0x3f)
+		if first[0]&0x40 == 0 {
+			tag = int(first[0]>>2) & 0x0f
+		}
+
+		if tag == tagSymmetricallyEncrypted {
+			return nil, errors.New("the OpenPGP message is encrypted without integrity protection, refusing to decrypt it")
+		}
+
+		if tag != tagPublicKeyEncryptedKey && tag != tagSymmetricKeyEncrypted && tag != tagMarker {
+			return io.MultiReader(consumed, buffered), nil
+		}
+
+		if _, length, err := packetHeader(buffered, consumed); err != nil {
+			return nil, err
+		} else if _, err = io.CopyN(consumed, buffered, int64(length)); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package pgp_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"util.tim/encrypto/adapters/pgp"
+)
+
+var testConfig = &packet.Config{RSABits: 1024}
+
+func noPrompt(description string) ([]byte, error) {
+	return nil, errors.New("unexpected prompt for " + description)
+}
+
+func newEntityWith(t *testing.T, name string, config *packet.Config) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", config)
+	if err != nil {
+		t.Log("NewEntity failed", err)
+		t.FailNow()
+	}
+
+	return entity
+}
+
+func newEntity(t *testing.T, name string) *openpgp.Entity {
+	return newEntityWith(t, name, testConfig)
+}
+
+// newKeyring holds the secret keys of secret and only the public keys of
+// public.
+func newKeyring(t *testing.T, secret []*openpgp.Entity, public []*openpgp.Entity) pgp.Keyring {
+	serialized := &bytes.Buffer{}
+	for _, entity := range secret {
+		if err := entity.SerializePrivate(serialized, testConfig); err != nil {
+			t.Log("SerializePrivate failed", err)
+			t.FailNow()
+		}
+	}
+	for _, entity := range public {
+		if err := entity.Serialize(serialized); err != nil {
+			t.Log("Serialize failed", err)
+			t.FailNow()
+		}
+	}
+
+	keyring, err := pgp.ReadKeyring(serialized.Bytes())
+	if err != nil {
+		t.Log("ReadKeyring failed", err)
+		t.FailNow()
+	}
+
+	return keyring
+}
+
+func encrypt(t *testing.T, keyring pgp.Keyring, options pgp.EncryptOptions, plaintext string) []byte {
+	encrypted := &bytes.Buffer{}
+	writer, err := keyring.Encrypt(encrypted, options, noPrompt)
+	if err != nil {
+		t.Log("Encrypt failed", err)
+		t.FailNow()
+	}
+
+	if _, err = writer.Write([]byte(plaintext)); err != nil {
+		t.Log("Write failed", err)
+		t.FailNow()
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	return encrypted.Bytes()
+}
+
+func Test_PublicKeyRoundTripWithSignature(t *testing.T) {
+	alice, bob := newEntity(t, "alice"), newEntity(t, "bob")
+	sender := newKeyring(t, []*openpgp.Entity{alice}, []*openpgp.Entity{bob})
+	receiver := newKeyring(t, []*openpgp.Entity{bob}, []*openpgp.Entity{alice})
+
+	encrypted := encrypt(t, sender, pgp.EncryptOptions{Recipients: []string{"bob"}, Signer: "alice", FileName: "note.txt"}, "hello bob")
+
+	if !pgp.IsMessage(encrypted) {
+		t.Log("Expected the output to be recognised as an OpenPGP message")
+		t.Fail()
+	}
+
+	message, err := receiver.Decrypt(bytes.NewReader(encrypted), noPrompt)
+	if err != nil {
+		t.Log("Decrypt failed", err)
+		t.FailNow()
+	}
+
+	plaintext, err := ioutil.ReadAll(message)
+	if err != nil || string(plaintext) != "hello bob" || message.FileName != "note.txt" || !message.Encrypted {
+		t.Log("Unexpected message", string(plaintext), message.FileName, message.Encrypted, err)
+		t.FailNow()
+	}
+
+	signer, err := message.Signer()
+	if err != nil || !strings.Contains(signer, "alice") {
+		t.Log("Expected a good signature from alice, received", signer, err)
+		t.Fail()
+	}
+}
+
+// Ed25519 with a Curve25519 subkey is what current gpg versions generate.
+func Test_EdDSAKeysRoundTrip(t *testing.T) {
+	dave := newEntityWith(t, "dave", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	keyring := newKeyring(t, []*openpgp.Entity{dave}, nil)
+
+	encrypted := encrypt(t, keyring, pgp.EncryptOptions{Recipients: []string{"dave"}, Signer: "dave"}, "hello dave")
+
+	message, err := keyring.Decrypt(bytes.NewReader(encrypted), noPrompt)
+	if err != nil {
+		t.Log("Decrypt failed", err)
+		t.FailNow()
+	}
+
+	plaintext, err := ioutil.ReadAll(message)
+	if err != nil || string(plaintext) != "hello dave" {
+		t.Log("Unexpected plaintext", string(plaintext), err)
+		t.FailNow()
+	}
+
+	if signer, err := message.Signer(); err != nil || !strings.Contains(signer, "dave") {
+		t.Log("Expected a good signature from dave, received", signer, err)
+		t.Fail()
+	}
+}
+
+func Test_PassphraseRoundTrip(t *testing.T) {
+	keyring := newKeyring(t, nil, nil)
+	encrypted := encrypt(t, keyring, pgp.EncryptOptions{Passphrase: []byte("correct horse"), Armor: true}, "hello")
+
+	if !bytes.HasPrefix(encrypted, []byte("-----BEGIN "+pgp.MessageBlock)) {
+		t.Log("Expected an armored message")
+		t.FailNow()
+	}
+
+	message, err := keyring.Decrypt(bytes.NewReader(encrypted), func(string) ([]byte, error) {
+		return []byte("correct horse"), nil
+	})
+	if err != nil {
+		t.Log("Decrypt failed", err)
+		t.FailNow()
+	}
+
+	plaintext, err := ioutil.ReadAll(message)
+	if err != nil || string(plaintext) != "hello" {
+		t.Log("Unexpected plaintext", string(plaintext), err)
+		t.FailNow()
+	}
+
+	if signer, err := message.Signer(); signer != "" || err != nil {
+		t.Log("Expected an unsigned message, received", signer, err)
+		t.Fail()
+	}
+}
+
+func Test_MessagesWithoutIntegrityProtectionAreRefused(t *testing.T) {
+	message := &bytes.Buffer{}
+	if _, err := packet.SerializeSymmetricKeyEncrypted(message, []byte("passphrase"), nil); err != nil {
+		t.Log("SerializeSymmetricKeyEncrypted failed", err)
+		t.FailNow()
+	}
+
+	// A new format symmetrically encrypted data packet, tag 9, which has no
+	// modification detection code.
+	message.Write([]byte{0xc0 | 9, 32})
+	message.Write(bytes.Repeat([]byte{1}, 32))
+
+	_, err := newKeyring(t, nil, nil).Decrypt(message, func(string) ([]byte, error) {
+		return []byte("passphrase"), nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "integrity") {
+		t.Log("Expected the message to be refused, received", err)
+		t.Fail()
+	}
+}
+
+func Test_TamperedSignaturesAreReported(t *testing.T) {
+	alice := newEntity(t, "alice")
+
+	signed := &bytes.Buffer{}
+	writer, err := openpgp.Sign(signed, alice, nil, &packet.Config{DefaultCompressionAlgo: packet.CompressionNone})
+	if err != nil {
+		t.Log("Sign failed", err)
+		t.FailNow()
+	}
+	writer.Write([]byte("pay alice 10"))
+	writer.Close()
+
+	tampered := bytes.Replace(signed.Bytes(), []byte("pay alice"), []byte("pay bobby"), 1)
+	if bytes.Equal(tampered, signed.Bytes()) {
+		t.Log("Expected the literal data to be stored uncompressed")
+		t.FailNow()
+	}
+
+	message, err := newKeyring(t, nil, []*openpgp.Entity{alice}).Decrypt(bytes.NewReader(tampered), noPrompt)
+	if err != nil {
+		t.Log("Decrypt failed", err)
+		t.FailNow()
+	}
+
+	if _, err = ioutil.ReadAll(message); err != nil {
+		t.Log("Read failed", err)
+		t.FailNow()
+	}
+
+	if signer, err := message.Signer(); err == nil || errors.Is(err, pgp.ErrUnknownSigner) {
+		t.Log("Expected a bad signature, received", signer, err)
+		t.Fail()
+	}
+}
+
+func Test_MissingKeysAreReported(t *testing.T) {
+	alice, bob, carol := newEntity(t, "alice"), newEntity(t, "bob"), newEntity(t, "carol")
+	sender := newKeyring(t, []*openpgp.Entity{alice}, []*openpgp.Entity{bob})
+	encrypted := encrypt(t, sender, pgp.EncryptOptions{Recipients: []string{"bob"}, Signer: "alice"}, "hello bob")
+
+	if _, err := newKeyring(t, []*openpgp.Entity{carol}, nil).Decrypt(bytes.NewReader(encrypted), noPrompt); err == nil {
+		t.Log("Expected decrypting without bob's secret key to fail")
+		t.Fail()
+	}
+
+	message, err := newKeyring(t, []*openpgp.Entity{bob}, nil).Decrypt(bytes.NewReader(encrypted), noPrompt)
+	if err != nil {
+		t.Log("Decrypt failed", err)
+		t.FailNow()
+	}
+
+	if _, err = ioutil.ReadAll(message); err != nil {
+		t.Log("Read failed", err)
+		t.FailNow()
+	}
+
+	if _, err = message.Signer(); !errors.Is(err, pgp.ErrUnknownSigner) {
+		t.Log("Expected the signer to be unknown, received", err)
+		t.Fail()
+	}
+
+	if _, err = sender.Encrypt(&bytes.Buffer{}, pgp.EncryptOptions{Recipients: []string{"carol"}}, noPrompt); err == nil {
+		t.Log("Expected encrypting to a key missing from the keyring to fail")
+		t.Fail()
+	}
+}
+
+func Test_DetachedSignatures(t *testing.T) {
+	alice := newEntity(t, "alice")
+	keyring := newKeyring(t, []*openpgp.Entity{alice}, nil)
+
+	signature := &bytes.Buffer{}
+	if err := keyring.Sign(signature, strings.NewReader("release 1.0"), "alice", noPrompt); err != nil {
+		t.Log("Sign failed", err)
+		t.FailNow()
+	}
+
+	signer, err := keyring.Verify(strings.NewReader("release 1.0"), bytes.NewReader(signature.Bytes()))
+	if err != nil || !strings.Contains(signer, "alice") {
+		t.Log("Expected a good signature from alice, received", signer, err)
+		t.Fail()
+	}
+
+	if _, err = keyring.Verify(strings.NewReader("release 1.1"), bytes.NewReader(signature.Bytes())); err == nil {
+		t.Log("Expected the signature not to match other content")
+		t.Fail()
+	}
+}
@@ -0,0 +1,104 @@
+package passphrase
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	StanzaType = "argon2id"
+	maxMemory  = 1024 * 1024
+	maxTime    = 64
+)
+
+type Params struct {
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+func DefaultParams() Params {
+	return Params{
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+}
+
+func (params Params) deriveKey(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), params.Salt, params.Time, params.Memory, params.Threads, 32)
+}
+
+type recipient struct {
+	passphrase string
+	params     Params
+}
+
+func (recipient *recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	params := recipient.params
+	params.Salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	body, err := fileformat.WrapKey(params.deriveKey(recipient.passphrase), fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{
+		Type:   StanzaType,
+		Params: encodedParams,
+		Body:   body,
+	}, nil
+}
+
+func NewRecipient(passphrase string, params Params) fileformat.Recipient {
+	return &recipient{
+		passphrase: passphrase,
+		params:     params,
+	}
+}
+
+type identity struct {
+	passphrase string
+}
+
+func (identity *identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaType {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 || len(params.Salt) == 0 {
+		return nil, errors.New("argon2id stanza has invalid parameters")
+	}
+
+	if params.Time > maxTime || params.Memory > maxMemory {
+		return nil, fmt.Errorf("argon2id parameters time [%d] memory [%d] exceed the supported limits", params.Time, params.Memory)
+	}
+
+	return fileformat.UnwrapKey(params.deriveKey(identity.passphrase), stanza.Body)
+}
+
+func NewIdentity(passphrase string) fileformat.Identity {
+	return &identity{
+		passphrase: passphrase,
+	}
+}
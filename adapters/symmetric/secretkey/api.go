@@ -0,0 +1,138 @@
+package secretkey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	StanzaType = "secret-key"
+	KeySize    = 32
+)
+
+type Params struct {
+	Id   string `json:"id"`
+	Salt []byte `json:"salt"`
+}
+
+// Id names a key without revealing it, so a file records which stored key
+// opens it.
+func Id(key []byte) string {
+	sum := sha256.Sum256(append([]byte("encrypto secret key id\n"), key...))
+
+	return base64.RawStdEncoding.EncodeToString(sum[:9])
+}
+
+func deriveKEK(key []byte, salt []byte) ([]byte, error) {
+	kek := make([]byte, 32)
+
+	_, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte("encrypto secret key stanza")), kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return kek, nil
+}
+
+func Generate() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+type recipient struct {
+	key []byte
+}
+
+func (recipient *recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	params := Params{
+		Id:   Id(recipient.key),
+		Salt: make([]byte, 16),
+	}
+
+	if _, err := io.ReadFull(rand.Reader, params.Salt); err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	kek, err := deriveKEK(recipient.key, params.Salt)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	body, err := fileformat.WrapKey(kek, fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	encodedParams, err := json.Marshal(params)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{
+		Type:   StanzaType,
+		Params: encodedParams,
+		Body:   body,
+	}, nil
+}
+
+func validate(key []byte) error {
+	if len(key) != KeySize {
+		return errors.New("secret keys must be 32 bytes")
+	}
+
+	return nil
+}
+
+func NewRecipient(key []byte) (fileformat.Recipient, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	return &recipient{key: key}, nil
+}
+
+type identity struct {
+	key []byte
+	id  string
+}
+
+func (identity *identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaType {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Id != identity.id {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	kek, err := deriveKEK(identity.key, params.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileformat.UnwrapKey(kek, stanza.Body)
+}
+
+func NewIdentity(key []byte) (fileformat.Identity, error) {
+	if err := validate(key); err != nil {
+		return nil, err
+	}
+
+	return &identity{key: key, id: Id(key)}, nil
+}
@@ -0,0 +1,227 @@
+package sharing
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/shamir"
+)
+
+const (
+	StanzaType = "shamir"
+	blockType  = "ENCRYPTO KEY SHARE"
+)
+
+type Params struct {
+	Group     string `json:"group"`
+	Threshold int    `json:"threshold"`
+	Total     int    `json:"total"`
+}
+
+type Share struct {
+	Params
+	shamir.Share
+}
+
+// Encode renders a share as printable PEM text.
+func (share Share) Encode() []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type: blockType,
+		Headers: map[string]string{
+			"Group":     share.Group,
+			"Threshold": strconv.Itoa(share.Threshold),
+			"Total":     strconv.Itoa(share.Total),
+			"Share":     strconv.Itoa(int(share.X)),
+		},
+		Bytes: share.Y,
+	})
+}
+
+func headerInt(block *pem.Block, name string, max int) (int, error) {
+	value, err := strconv.Atoi(block.Headers[name])
+	if err != nil || value < 1 || value > max {
+		return 0, fmt.Errorf("share has an invalid %s [%s]", name, block.Headers[name])
+	}
+
+	return value, nil
+}
+
+// ParseShares reads every share in encoded, so several printed shares can
+// be kept in one file.
+func ParseShares(encoded []byte) ([]Share, error) {
+	shares := []Share{}
+
+	for {
+		block, rest := pem.Decode(encoded)
+		if block == nil {
+			break
+		}
+		encoded = rest
+
+		if block.Type != blockType {
+			continue
+		}
+
+		share, err := parseBlock(block)
+		if err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, share)
+	}
+
+	if len(shares) == 0 {
+		return nil, errors.New("input does not contain an encrypto key share")
+	}
+
+	return shares, nil
+}
+
+func parseBlock(block *pem.Block) (Share, error) {
+	share := Share{}
+	share.Group = block.Headers["Group"]
+	share.Y = block.Bytes
+
+	var err error
+	if share.Threshold, err = headerInt(block, "Threshold", 255); err != nil {
+		return Share{}, err
+	}
+	if share.Total, err = headerInt(block, "Total", 255); err != nil {
+		return Share{}, err
+	}
+
+	x, err := headerInt(block, "Share", share.Total)
+	if err != nil {
+		return Share{}, err
+	}
+	share.X = byte(x)
+
+	return share, nil
+}
+
+// Recipient wraps the file key under a random key encryption key and splits
+// that key into shares, so the shares can be handed out before or after the
+// file is written and reveal nothing about the file key on their own.
+type Recipient interface {
+	fileformat.Recipient
+	Shares() []Share
+}
+
+type recipient struct {
+	params Params
+	kek    []byte
+	shares []Share
+}
+
+func (recipient *recipient) Shares() []Share {
+	return recipient.shares
+}
+
+func (recipient *recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	body, err := fileformat.WrapKey(recipient.kek, fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	encodedParams, err := json.Marshal(recipient.params)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{
+		Type:   StanzaType,
+		Params: encodedParams,
+		Body:   body,
+	}, nil
+}
+
+func NewRecipient(total int, threshold int) (Recipient, error) {
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, kek); err != nil {
+		return nil, err
+	}
+
+	group := make([]byte, 9)
+	if _, err := io.ReadFull(rand.Reader, group); err != nil {
+		return nil, err
+	}
+
+	split, err := shamir.Split(kek, total, threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	params := Params{
+		Group:     base64.RawStdEncoding.EncodeToString(group),
+		Threshold: threshold,
+		Total:     total,
+	}
+
+	shares := make([]Share, len(split))
+	for i, share := range split {
+		shares[i] = Share{Params: params, Share: share}
+	}
+
+	return &recipient{params: params, kek: kek, shares: shares}, nil
+}
+
+type identity struct {
+	shares []Share
+}
+
+func (identity *identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != StanzaType {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	var params Params
+	if err := json.Unmarshal(stanza.Params, &params); err != nil {
+		return nil, err
+	}
+
+	matching := []shamir.Share{}
+	seen := map[byte]bool{}
+	for _, share := range identity.shares {
+		if share.Group == params.Group && !seen[share.X] {
+			matching = append(matching, share.Share)
+			seen[share.X] = true
+		}
+	}
+
+	if len(matching) == 0 {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	if len(matching) < params.Threshold {
+		return nil, fmt.Errorf("[%d] of [%d] required shares were supplied for group [%s]", len(matching), params.Threshold, params.Group)
+	}
+
+	kek, err := shamir.Combine(matching)
+	if err != nil {
+		return nil, err
+	}
+
+	fileKey, err := fileformat.UnwrapKey(kek, stanza.Body)
+	if err != nil {
+		return nil, fmt.Errorf("shares for group [%s] did not combine to the right key: %w", params.Group, err)
+	}
+
+	return fileKey, nil
+}
+
+// NewIdentity holds shares that are combined when a stanza for their group
+// is found. Shares from several groups may be mixed.
+func NewIdentity(shares []Share) (fileformat.Identity, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares were supplied")
+	}
+
+	return &identity{shares: shares}, nil
+}
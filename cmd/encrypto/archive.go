@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"util.tim/encrypto/core/archive"
+	"util.tim/encrypto/core/fileformat"
+)
+
+func runArchive(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of create, list or extract")
+	}
+
+	switch args[0] {
+	case "create":
+		return runArchiveCreate(args[1:])
+	case "list":
+		return runArchiveList(args[1:])
+	case "extract":
+		return runArchiveExtract(args[1:])
+	default:
+		return newUsageError("unknown archive command [%s], expected one of create, list or extract", args[0])
+	}
+}
+
+func runArchiveCreate(args []string) error {
+	flags := newFlagSet("archive create", "DIRECTORY")
+	outPath := flags.String("o", stdio, "output `file`")
+	recipientOptions := addRecipientFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	root, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(root); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("[%s] is not a directory", root)
+	}
+
+	recipients, err := recipientOptions.recipients()
+	if err != nil {
+		return err
+	}
+
+	return withOutput(*outPath, 0644, func(output io.Writer) error {
+		writer, err := fileformat.NewWriter(output, recipients...)
+		if err != nil {
+			return err
+		}
+
+		err = archive.Create(writer, root, func(path string, reason string) {
+			fmt.Fprintf(os.Stderr, "skipping [%s]: %s\n", path, reason)
+		})
+		if err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+}
+
+// openArchive needs a regular file rather than a stream, because listing and
+// single file extraction only decrypt the chunks they touch.
+func openArchive(path string, identityOptions *identityOptions) (archive.Archive, func() error, error) {
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	plaintext, err := fileformat.NewReaderAt(file, info.Size(), identities...)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	opened, err := archive.Open(plaintext, plaintext.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	return opened, file.Close, nil
+}
+
+func runArchiveList(args []string) error {
+	flags := newFlagSet("archive list", "FILE")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	opened, closeArchive, err := openArchive(path, identityOptions)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+
+	for _, entry := range opened.Entries() {
+		mode := entry.Mode
+		if entry.Type == archive.TypeDirectory {
+			mode |= os.ModeDir
+		}
+
+		fmt.Printf("%s %10d %s %s\n", mode, entry.Size, entry.ModTime.Format("2006-01-02 15:04"), entry.Path)
+	}
+
+	return nil
+}
+
+func runArchiveExtract(args []string) error {
+	flags := newFlagSet("archive extract", "FILE [PATH...]")
+	outDir := flags.String("o", ".", "extract into `directory`")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return newUsageError("expected an archive file")
+	}
+
+	opened, closeArchive, err := openArchive(flags.Arg(0), identityOptions)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+
+	if flags.NArg() == 1 {
+		return opened.ExtractAll(*outDir)
+	}
+
+	for _, path := range flags.Args()[1:] {
+		entry, found := opened.Find(path)
+		if !found {
+			return fmt.Errorf("[%s] is not in the archive", path)
+		}
+
+		if err = opened.Extract(entry, *outDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
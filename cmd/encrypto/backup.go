@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"util.tim/encrypto/core/backup"
+)
+
+func runBackup(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of init, create or list")
+	}
+
+	switch args[0] {
+	case "init":
+		return runBackupInit(args[1:])
+	case "create":
+		return runBackupCreate(args[1:])
+	case "list":
+		return runBackupList(args[1:])
+	default:
+		return newUsageError("unknown backup command [%s], expected one of init, create or list", args[0])
+	}
+}
+
+func runBackupInit(args []string) error {
+	flags := newFlagSet("backup init", "REPOSITORY")
+	recipientOptions := addRecipientFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	dir, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	if err := recipientOptions.shares.validate(filepath.Join(dir, "config")); err != nil {
+		return err
+	}
+
+	recipients, err := recipientOptions.recipients()
+	if err != nil {
+		return err
+	}
+
+	if _, err = backup.Init(dir, backup.DefaultChunkParams, recipients...); err != nil {
+		return err
+	}
+
+	return recipientOptions.shares.write()
+}
+
+func openRepository(dir string, identityOptions *identityOptions) (backup.Repository, error) {
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return nil, err
+	}
+
+	return backup.Open(dir, identities...)
+}
+
+func runBackupCreate(args []string) error {
+	flags := newFlagSet("backup create", "REPOSITORY DIRECTORY")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return newUsageError("expected a repository and a directory, received [%d] arguments", flags.NArg())
+	}
+
+	root := flags.Arg(1)
+	if info, err := os.Stat(root); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("[%s] is not a directory", root)
+	}
+
+	repository, err := openRepository(flags.Arg(0), identityOptions)
+	if err != nil {
+		return err
+	}
+
+	snapshot, stats, err := repository.Backup(root, func(path string, reason string) {
+		fmt.Fprintf(os.Stderr, "skipping [%s]: %s\n", path, reason)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "snapshot %s: %d files, %d unchanged, %d bytes, %d new chunks with %d bytes\n",
+		snapshot.Id, stats.Files, stats.UnchangedFiles, stats.Bytes, stats.NewChunks, stats.NewBytes)
+
+	return nil
+}
+
+func runBackupList(args []string) error {
+	flags := newFlagSet("backup list", "REPOSITORY")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	dir, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	repository, err := openRepository(dir, identityOptions)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := repository.Snapshots()
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		files, size := 0, int64(0)
+		for _, entry := range snapshot.Entries {
+			if entry.Type == backup.TypeFile {
+				files++
+				size += entry.Size
+			}
+		}
+
+		fmt.Printf("%s %s %6d files %12d bytes %s\n", snapshot.Id, snapshot.Time.Local().Format("2006-01-02 15:04"), files, size, snapshot.Root)
+	}
+
+	return nil
+}
+
+func runRestore(args []string) error {
+	flags := newFlagSet("restore", "REPOSITORY [PATH...]")
+	snapshotId := flags.String("snapshot", backup.Latest, "restore the snapshot with this `id`")
+	outDir := flags.String("o", ".", "restore into `directory`")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return newUsageError("expected a repository")
+	}
+
+	repository, err := openRepository(flags.Arg(0), identityOptions)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := repository.Snapshot(*snapshotId)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 1 {
+		return repository.RestoreAll(snapshot, *outDir)
+	}
+
+	// A path restores the entry itself and everything below it, so naming a
+	// directory brings back its contents.
+	requested := map[string]bool{}
+	for _, name := range flags.Args()[1:] {
+		requested[path.Clean(filepath.ToSlash(name))] = false
+	}
+
+	selected := snapshot
+	selected.Entries = []backup.Entry{}
+	for _, entry := range snapshot.Entries {
+		matched := false
+		for name := range requested {
+			if entry.Path == name || strings.HasPrefix(entry.Path, name+"/") {
+				requested[name] = true
+				matched = true
+			}
+		}
+
+		if matched {
+			selected.Entries = append(selected.Entries, entry)
+		}
+	}
+
+	for name, found := range requested {
+		if !found {
+			return fmt.Errorf("[%s] is not in snapshot [%s]", name, snapshot.Id)
+		}
+	}
+
+	return repository.RestoreAll(selected, *outDir)
+}
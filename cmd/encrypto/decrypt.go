@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"util.tim/encrypto/adapters/pgp"
+	"util.tim/encrypto/core/armor"
+	"util.tim/encrypto/core/fileformat"
+)
+
+func runDecrypt(args []string) error {
+	flags := newFlagSet("decrypt", "[INPUT]")
+	outPath := flags.String("o", stdio, "output `file`")
+	restore := flags.Bool("restore", false, "write to the original file name and restore its mode and mtime")
+	verifyKeyPaths := stringList{}
+	flags.Var(&verifyKeyPaths, "verify-key", "require a signature from the public key in PEM `file` before decrypting, may be repeated")
+	signaturePath := flags.String("signature", "", "read the signature from `file`, defaults to INPUT.sig")
+	identityOptions := addIdentityFlags(flags)
+	pgpOptions := addPGPKeyringFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if len(verifyKeyPaths) > 0 {
+		if inPath == stdio {
+			return newUsageError("signatures can only be checked when decrypting a file")
+		}
+
+		sigPath, err := signaturePathFor(inPath, *signaturePath)
+		if err != nil {
+			return err
+		}
+
+		verifiers, err := loadVerifiers(verifyKeyPaths)
+		if err != nil {
+			return err
+		}
+
+		if _, err = verifyFile(inPath, sigPath, verifiers); err != nil {
+			return err
+		}
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// OpenPGP messages are told apart before any identity is loaded, since
+	// loading them may prompt for a passphrase the message does not use.
+	buffered := bufio.NewReader(input)
+	if prefix, _ := buffered.Peek(64); pgp.IsMessage(prefix) {
+		return decryptPGP(buffered, *outPath, *restore, pgpOptions, *identityOptions.passphraseFile)
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	raw, _, err := armor.Detect(buffered)
+	if err != nil {
+		return err
+	}
+
+	reader, err := fileformat.NewReader(raw, identities...)
+	if err != nil {
+		return err
+	}
+
+	metadata := reader.Metadata()
+	if *restore && *outPath == stdio {
+		if *outPath, err = restorePath(metadata); err != nil {
+			return err
+		}
+	}
+
+	err = withOutput(*outPath, 0600, func(output io.Writer) error {
+		written, err := io.Copy(output, reader)
+		if err != nil {
+			return err
+		}
+
+		if metadata != nil && written != metadata.Size {
+			return fmt.Errorf("decrypted [%d] bytes but the header records [%d]", written, metadata.Size)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if *restore && *outPath != stdio {
+		return restoreAttributes(*outPath, metadata)
+	}
+
+	return nil
+}
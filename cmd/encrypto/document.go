@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"util.tim/encrypto/core/document"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const maxDocumentSize = 16 * 1024 * 1024
+
+func readDocument(path string, format string) (document.Document, string, error) {
+	input, err := openInput(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer input.Close()
+
+	contents, err := ioutil.ReadAll(io.LimitReader(input, maxDocumentSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(contents) > maxDocumentSize {
+		return nil, "", fmt.Errorf("[%s] is larger than [%d] bytes", path, maxDocumentSize)
+	}
+
+	if format == "" {
+		format = document.FormatFor(path, contents)
+	}
+
+	doc, err := document.Parse(contents, format)
+	return doc, format, err
+}
+
+func writeDocument(path string, doc document.Document, perm os.FileMode) error {
+	encoded, err := doc.Encode()
+	if err != nil {
+		return err
+	}
+
+	return withOutput(path, perm, func(output io.Writer) error {
+		_, err := output.Write(encoded)
+		return err
+	})
+}
+
+func runDocument(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of encrypt, decrypt or edit")
+	}
+
+	switch args[0] {
+	case "encrypt":
+		return runDocumentEncrypt(args[1:])
+	case "decrypt":
+		return runDocumentDecrypt(args[1:])
+	case "edit":
+		return runDocumentEdit(args[1:])
+	default:
+		return newUsageError("unknown doc command [%s], expected one of encrypt, decrypt or edit", args[0])
+	}
+}
+
+func addFormatFlag(flags *flag.FlagSet) *string {
+	return flags.String("format", "", "document `format`, json or yaml, defaults to the file extension")
+}
+
+func runDocumentEncrypt(args []string) error {
+	flags := newFlagSet("doc encrypt", "[INPUT]")
+	outPath := flags.String("o", stdio, "output `file`")
+	format := addFormatFlag(flags)
+	cipherName := flags.String("cipher", fileformat.CipherAESGCM, "value `cipher`, one of "+strings.Join(fileformat.Ciphers, ", "))
+	recipientOptions := addRecipientFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if err := fileformat.ValidateCipher(*cipherName); err != nil {
+		return newUsageError("%s", err)
+	}
+
+	if err := recipientOptions.shares.validate(*outPath); err != nil {
+		return err
+	}
+
+	doc, _, err := readDocument(inPath, *format)
+	if err != nil {
+		return err
+	}
+
+	if doc.Encrypted() {
+		return fmt.Errorf("[%s] already has an [%s] section", inPath, document.MetadataKey)
+	}
+
+	recipients, err := recipientOptions.recipients()
+	if err != nil {
+		return err
+	}
+
+	keys, err := document.NewKeys(*cipherName, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if err = doc.Seal(keys); err != nil {
+		return err
+	}
+
+	if err = writeDocument(*outPath, doc, 0644); err != nil {
+		return err
+	}
+
+	return recipientOptions.shares.write()
+}
+
+func runDocumentDecrypt(args []string) error {
+	flags := newFlagSet("doc decrypt", "[INPUT]")
+	outPath := flags.String("o", stdio, "output `file`")
+	format := addFormatFlag(flags)
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	doc, _, err := readDocument(inPath, *format)
+	if err != nil {
+		return err
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	if _, err = doc.Open(identities...); err != nil {
+		return err
+	}
+
+	return writeDocument(*outPath, doc, 0600)
+}
+
+func runDocumentEdit(args []string) error {
+	flags := newFlagSet("doc edit", "FILE")
+	format := addFormatFlag(flags)
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	doc, docFormat, err := readDocument(path, *format)
+	if err != nil {
+		return err
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	keys, err := doc.Open(identities...)
+	if err != nil {
+		return err
+	}
+
+	dir, err := privateTempDir()
+	if err != nil {
+		return err
+	}
+	plainPath := filepath.Join(dir, "document."+docFormat)
+	defer func() {
+		if err := wipeFile(plainPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "could not wipe [%s]: %s\n", plainPath, err)
+		}
+		os.RemoveAll(dir)
+	}()
+
+	if err = writeDocument(plainPath, doc, 0600); err != nil {
+		return err
+	}
+
+	original, err := ioutil.ReadFile(plainPath)
+	if err != nil {
+		return err
+	}
+
+	// A broken edit reopens the editor rather than losing the changes,
+	// until the document parses or is saved without further changes.
+	var editedDoc document.Document
+	for {
+		if err = runEditor(plainPath); err != nil {
+			return err
+		}
+
+		edited, err := ioutil.ReadFile(plainPath)
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(original, edited) {
+			fmt.Fprintln(os.Stderr, "no changes, nothing was saved")
+			return nil
+		}
+
+		editedDoc, err = document.Parse(edited, docFormat)
+		if err == nil && editedDoc.Encrypted() {
+			err = fmt.Errorf("the document must not contain an [%s] section", document.MetadataKey)
+		}
+		if err == nil {
+			break
+		}
+
+		fmt.Fprintf(os.Stderr, "the edited document is not valid: %s\n", err)
+		fmt.Fprintln(os.Stderr, "reopening the editor, exit without saving to give up")
+		original = edited
+	}
+
+	if err = editedDoc.Seal(keys); err != nil {
+		return err
+	}
+
+	encoded, err := editedDoc.Encode()
+	if err != nil {
+		return err
+	}
+
+	return rewriteFile(path, func(dst io.Writer, _ io.Reader) error {
+		_, err := dst.Write(encoded)
+		return err
+	})
+}
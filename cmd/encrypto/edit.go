@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"util.tim/encrypto/core/armor"
+	"util.tim/encrypto/core/fileformat"
+)
+
+// privateTempDir prefers a memory backed file system so the plaintext never
+// reaches a disk, and falls back to the usual temp dir. Either way the
+// directory is only accessible to the current user.
+func privateTempDir() (string, error) {
+	base := ""
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		base = "/dev/shm"
+	}
+
+	dir, err := ioutil.TempDir(base, "encrypto-edit-")
+	if err != nil && base != "" {
+		dir, err = ioutil.TempDir("", "encrypto-edit-")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return dir, os.Chmod(dir, 0700)
+}
+
+// wipeFile overwrites path with zeros before removing it. Journaling and copy
+// on write file systems may still keep old blocks, which is why edit prefers
+// tmpfs in the first place.
+func wipeFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err == nil {
+		_, err = io.CopyN(file, zeroReader{}, info.Size())
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	removeErr := os.Remove(path)
+	if err == nil {
+		err = removeErr
+	}
+
+	return err
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+
+	return []string{"vi"}
+}
+
+// editName keeps the original file name, and so its extension, for the
+// temporary copy so editors pick the right syntax highlighting.
+func editName(path string, metadata *fileformat.Metadata) string {
+	if metadata != nil {
+		name := filepath.Base(metadata.Name)
+		if name != "." && name != ".." && name != string(filepath.Separator) && !strings.ContainsAny(name, "/\\") {
+			return name
+		}
+	}
+
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func runEditor(path string) error {
+	command := editorCommand()
+
+	editor := exec.Command(command[0], append(command[1:], path)...)
+	editor.Stdin = os.Stdin
+	editor.Stdout = os.Stdout
+	editor.Stderr = os.Stderr
+
+	// The editor shares the terminal, so an interrupt meant for it, or the
+	// terminal closing, must not kill edit before the plaintext is wiped.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(interrupts)
+
+	if err := editor.Run(); err != nil {
+		return fmt.Errorf("editor [%s] failed, the file was left unchanged: %w", command[0], err)
+	}
+
+	return nil
+}
+
+func fileHash(path string) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return hasher.Sum(nil), size, nil
+}
+
+func runEdit(args []string) error {
+	flags := newFlagSet("edit", "FILE")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	before, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	raw, isArmored, err := armor.Detect(input)
+	if err != nil {
+		return err
+	}
+
+	reader, err := fileformat.NewReader(raw, identities...)
+	if err != nil {
+		return err
+	}
+
+	dir, err := privateTempDir()
+	if err != nil {
+		return err
+	}
+	plainPath := filepath.Join(dir, editName(path, reader.Metadata()))
+	defer func() {
+		if err := wipeFile(plainPath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "could not wipe [%s]: %s\n", plainPath, err)
+		}
+		os.RemoveAll(dir)
+	}()
+
+	err = withOutput(plainPath, 0600, func(output io.Writer) error {
+		_, err := io.Copy(output, reader)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	original, _, err := fileHash(plainPath)
+	if err != nil {
+		return err
+	}
+
+	if err = runEditor(plainPath); err != nil {
+		return err
+	}
+
+	edited, size, err := fileHash(plainPath)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(original, edited) {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+
+	var metadata *fileformat.Metadata
+	if reader.Metadata() != nil {
+		updated := *reader.Metadata()
+		updated.Size = size
+		updated.ModTime = time.Now()
+		metadata = &updated
+	}
+
+	reseal := func(dst io.Writer) error {
+		plaintext, err := os.Open(plainPath)
+		if err != nil {
+			return err
+		}
+		defer plaintext.Close()
+
+		writer, err := reader.Reseal(dst, metadata)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(writer, plaintext); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	}
+
+	// Someone else rewrote the file while the editor was open, so keep both
+	// versions rather than silently dropping either.
+	after, err := os.Stat(path)
+	if err != nil || !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		conflictPath := path + ".edit"
+		err = withOutput(conflictPath, before.Mode().Perm(), func(output io.Writer) error {
+			return withArmor(output, isArmored, reseal)
+		})
+		if err != nil {
+			return err
+		}
+
+		return errors.New("the file changed while it was being edited, the edit was saved encrypted to " + conflictPath)
+	}
+
+	return rewriteFile(path, func(dst io.Writer, _ io.Reader) error {
+		return reseal(dst)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+func runEncrypt(args []string) error {
+	flags := newFlagSet("encrypt", "[INPUT]")
+	outPath := flags.String("o", stdio, "output `file`")
+	useArmor := flags.Bool("armor", false, "write ASCII armored text instead of binary")
+	cipherName := flags.String("cipher", fileformat.CipherAESGCM, "payload `cipher`, one of "+strings.Join(fileformat.Ciphers, ", "))
+	noMetadata := flags.Bool("no-metadata", false, "do not record the input's name, size, mode and mtime")
+	signKeyPath := flags.String("sign-key", "", "sign the encrypted output with the private key in PEM `file`")
+	signaturePath := flags.String("signature", "", "write the signature to `file`, defaults to OUTPUT.sig")
+	recipientOptions := addRecipientFlags(flags)
+	usePGP := flags.Bool("pgp", false, "write an OpenPGP message instead, to -pgp-recipient keys or to a passphrase")
+	pgpEncryption := &pgpEncryption{options: addPGPKeyringFlags(flags)}
+	flags.Var(&pgpEncryption.recipients, "pgp-recipient", "encrypt an OpenPGP message to the keyring key with this fingerprint, key ID or user ID `query`, may be repeated")
+	flags.StringVar(&pgpEncryption.signer, "pgp-sign", "", "sign the OpenPGP message with the keyring secret key matching `query`")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if *usePGP || len(pgpEncryption.recipients) > 0 || pgpEncryption.signer != "" {
+		if err := checkPGPEncryptFlags(flags); err != nil {
+			return err
+		}
+
+		if *outPath == stdio && !*useArmor && term.IsTerminal(int(os.Stdout.Fd())) {
+			return errors.New("refusing to write a binary OpenPGP message to a terminal, use -armor or -o")
+		}
+
+		if *recipientOptions.usePassphrase && len(pgpEncryption.recipients) > 0 {
+			return newUsageError("an OpenPGP message is encrypted either to -pgp-recipient keys or to a passphrase")
+		}
+
+		pgpEncryption.passphraseFile = *recipientOptions.passphraseFile
+		pgpEncryption.armor = *useArmor
+
+		return pgpEncryption.run(inPath, *outPath)
+	}
+
+	if *outPath == stdio && !*useArmor && term.IsTerminal(int(os.Stdout.Fd())) {
+		return errors.New("refusing to write binary ciphertext to a terminal, use -armor or -o")
+	}
+
+	if err := fileformat.ValidateCipher(*cipherName); err != nil {
+		return newUsageError("%s", err)
+	}
+
+	if err := recipientOptions.shares.validate(*outPath); err != nil {
+		return err
+	}
+
+	signing := newSigningWriter(nil)
+	sigPath := ""
+	if *signKeyPath != "" {
+		if sigPath, err = signaturePathFor(*outPath, *signaturePath); err != nil {
+			return err
+		}
+
+		if signing.signer, err = loadSigner(*signKeyPath); err != nil {
+			return err
+		}
+	}
+
+	recipients, err := recipientOptions.recipients()
+	if err != nil {
+		return err
+	}
+
+	options := fileformat.Options{Cipher: *cipherName}
+	if inPath != stdio && !*noMetadata {
+		if options.Metadata, err = metadataFor(inPath); err != nil {
+			return err
+		}
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	err = withOutput(*outPath, 0644, func(output io.Writer) error {
+		return withArmor(signing.wrap(output), *useArmor, func(output io.Writer) error {
+			writer, err := fileformat.NewWriterWithOptions(output, options, recipients...)
+			if err != nil {
+				return err
+			}
+
+			written, err := io.Copy(writer, input)
+			if err != nil {
+				return err
+			}
+
+			if options.Metadata != nil && written != options.Metadata.Size {
+				return fmt.Errorf("[%s] changed size while it was being encrypted", inPath)
+			}
+
+			return writer.Close()
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := recipientOptions.shares.write(); err != nil {
+		return err
+	}
+
+	// The signature covers the bytes as written, so it can be checked
+	// before anyone decrypts.
+	return signing.writeSignature(sigPath)
+}
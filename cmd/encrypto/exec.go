@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"util.tim/encrypto/core/armor"
+	"util.tim/encrypto/core/dotenv"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const maxEnvFileSize = 16 * 1024 * 1024
+
+// exitStatus carries a child's exit code back out through main without any
+// message of our own.
+type exitStatus int
+
+func (status exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(status))
+}
+
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// loadEnvFile decrypts and parses an encrypted env file entirely in memory.
+func loadEnvFile(path string, identities []fileformat.Identity) ([]dotenv.Variable, error) {
+	input, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	raw, _, err := armor.Detect(input)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := fileformat.NewReader(raw, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := ioutil.ReadAll(io.LimitReader(reader, maxEnvFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plaintext) > maxEnvFileSize {
+		return nil, fmt.Errorf("[%s] is larger than [%d] bytes", path, maxEnvFileSize)
+	}
+
+	variables, err := dotenv.Parse(bytes.NewReader(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse [%s]: %w", path, err)
+	}
+
+	return variables, nil
+}
+
+func runExec(args []string) error {
+	flags := newFlagSet("exec", "-f FILE [-f FILE...] -- COMMAND [ARGS...]")
+	envPaths := stringList{}
+	flags.Var(&envPaths, "f", "add the variables in the encrypted env `file`, may be repeated and later files win")
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if len(envPaths) == 0 {
+		return newUsageError("at least one -f is required")
+	}
+
+	if flags.NArg() == 0 {
+		return newUsageError("expected a command to run after --")
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	variables := []dotenv.Variable{}
+	for _, path := range envPaths {
+		loaded, err := loadEnvFile(path, identities)
+		if err != nil {
+			return err
+		}
+
+		variables = append(variables, loaded...)
+	}
+
+	child := exec.Command(flags.Arg(0), flags.Args()[1:]...)
+	child.Env = dotenv.Environ(os.Environ(), variables)
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	signals := make(chan os.Signal, 8)
+	signal.Notify(signals, forwardedSignals...)
+
+	if err = child.Start(); err != nil {
+		signal.Stop(signals)
+		return err
+	}
+
+	go func() {
+		for received := range signals {
+			child.Process.Signal(received)
+		}
+	}()
+
+	err = child.Wait()
+	signal.Stop(signals)
+	close(signals)
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	// Follow the shell convention of 128 plus the signal number when the
+	// child was killed by a signal.
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return exitStatus(128 + int(status.Signal()))
+	}
+
+	return exitStatus(exitErr.ExitCode())
+}
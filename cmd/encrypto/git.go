@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"util.tim/encrypto/core/deterministic"
+)
+
+const gitFilterName = "encrypto"
+
+func git(args ...string) (string, error) {
+	output := bytes.NewBuffer(nil)
+
+	command := exec.Command("git", args...)
+	command.Stdout = output
+	command.Stderr = os.Stderr
+	if err := command.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
+
+// gitKeyPath keeps the key inside the git directory, so it is shared by every
+// worktree and can never be committed.
+func gitKeyPath() (string, error) {
+	dir, err := git("rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "encrypto", "key"), nil
+}
+
+func parseGitKey(contents []byte) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(contents)))
+	if err != nil || len(key) != deterministic.KeySize {
+		return nil, errors.New("not a repository key")
+	}
+
+	return key, nil
+}
+
+func readGitKey() ([]byte, error) {
+	path, err := gitKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGitKey(contents)
+}
+
+func installGitKey(key []byte) error {
+	path, err := gitKeyPath()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("the repository already has a key at [%s]", path)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(file, base64.StdEncoding.EncodeToString(key))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func configureGitFilters() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	quoted := "'" + strings.Replace(executable, "'", `'\''`, -1) + "'"
+	settings := [][2]string{
+		{"filter." + gitFilterName + ".clean", quoted + " git clean"},
+		{"filter." + gitFilterName + ".smudge", quoted + " git smudge"},
+		{"filter." + gitFilterName + ".required", "true"},
+		{"diff." + gitFilterName + ".textconv", quoted + " git textconv"},
+	}
+
+	for _, setting := range settings {
+		if _, err := git("config", setting[0], setting[1]); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "add lines like this to .gitattributes to encrypt matching files:\n")
+	fmt.Fprintf(os.Stderr, "  secrets/** filter=%s diff=%s\n", gitFilterName, gitFilterName)
+
+	return nil
+}
+
+func runGit(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of init, unlock, export-key, clean, smudge or textconv")
+	}
+
+	switch args[0] {
+	case "init":
+		return runGitInit(args[1:])
+	case "unlock":
+		return runGitUnlock(args[1:])
+	case "export-key":
+		return runGitExportKey(args[1:])
+	case "clean":
+		return runGitClean(args[1:])
+	case "smudge":
+		return runGitSmudge(args[1:])
+	case "textconv":
+		return runGitTextconv(args[1:])
+	default:
+		return newUsageError("unknown git command [%s], expected one of init, unlock, export-key, clean, smudge or textconv", args[0])
+	}
+}
+
+func runGitInit(args []string) error {
+	flags := newFlagSet("git init", "")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	key := make([]byte, deterministic.KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+
+	if err := installGitKey(key); err != nil {
+		return err
+	}
+
+	return configureGitFilters()
+}
+
+func runGitUnlock(args []string) error {
+	flags := newFlagSet("git unlock", "[KEY-FILE]")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	keyPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(keyPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	contents, err := ioutil.ReadAll(io.LimitReader(input, 1024))
+	if err != nil {
+		return err
+	}
+
+	key, err := parseGitKey(contents)
+	if err != nil {
+		return fmt.Errorf("[%s] is %w", keyPath, err)
+	}
+
+	if err = installGitKey(key); err != nil {
+		return err
+	}
+
+	if err = configureGitFilters(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "files checked out while locked still hold ciphertext, check them out again to decrypt them")
+	return nil
+}
+
+func runGitExportKey(args []string) error {
+	flags := newFlagSet("git export-key", "")
+	outPath := flags.String("o", stdio, "write the key to `file`, pipe it to encrypt to share it safely")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	key, err := readGitKey()
+	if err != nil {
+		return err
+	}
+
+	return withOutput(*outPath, 0600, func(output io.Writer) error {
+		_, err := fmt.Fprintln(output, base64.StdEncoding.EncodeToString(key))
+		return err
+	})
+}
+
+// The filters read a whole file at once because the synthetic IV depends on
+// all of the plaintext. Git hands over one file per run, so this is bounded
+// by the largest file being filtered.
+
+func runGitClean(args []string) error {
+	plaintext, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	// Without the key the filter must fail, committing plaintext would be
+	// far worse than a failed add.
+	key, err := readGitKey()
+	if err != nil {
+		return fmt.Errorf("refusing to store an unencrypted file, the repository key is not available: %w", err)
+	}
+
+	// Only content that opens under the repository key is already clean. A
+	// file that merely starts with the magic, or ciphertext under another
+	// key, is encrypted like any other and smudges back to the same bytes.
+	if _, err = deterministic.Decrypt(key, plaintext); err == nil {
+		_, err = os.Stdout.Write(plaintext)
+		return err
+	}
+
+	encrypted, err := deterministic.Encrypt(key, plaintext)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(encrypted)
+	return err
+}
+
+func runGitSmudge(args []string) error {
+	contents, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if !deterministic.IsEncrypted(contents) {
+		_, err = os.Stdout.Write(contents)
+		return err
+	}
+
+	// A clone without the key checks out ciphertext, so it stays usable
+	// until it is unlocked.
+	key, err := readGitKey()
+	if os.IsNotExist(err) {
+		_, err = os.Stdout.Write(contents)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := deterministic.Decrypt(key, contents)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(plaintext)
+	return err
+}
+
+func runGitTextconv(args []string) error {
+	flags := newFlagSet("git textconv", "FILE")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !deterministic.IsEncrypted(contents) {
+		_, err = os.Stdout.Write(contents)
+		return err
+	}
+
+	key, err := readGitKey()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := deterministic.Decrypt(key, contents)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(plaintext)
+	return err
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"util.tim/encrypto/adapters/symmetric/passphrase"
+	"util.tim/encrypto/adapters/symmetric/sharing"
+	"util.tim/encrypto/core/armor"
+	"util.tim/encrypto/core/fileformat"
+)
+
+func describeStanza(stanza fileformat.Stanza) string {
+	switch stanza.Type {
+	case passphrase.StanzaType:
+		var params passphrase.Params
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("time=%d memory=%dKiB threads=%d", params.Time, params.Memory, params.Threads)
+	case sharing.StanzaType:
+		var params sharing.Params
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("group=%s threshold=%d of %d", params.Group, params.Threshold, params.Total)
+	default:
+		return stanzaFingerprint(stanza)
+	}
+}
+
+func runInspect(args []string) error {
+	flags := newFlagSet("inspect", "[INPUT]")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	raw, isArmored, err := armor.Detect(input)
+	if err != nil {
+		return err
+	}
+
+	description, err := fileformat.Inspect(raw)
+	if err != nil {
+		return err
+	}
+
+	header := description.Header
+	fmt.Printf("version:    %d\n", header.Version)
+	fmt.Printf("armored:    %t\n", isArmored)
+	fmt.Printf("cipher:     %s\n", header.Cipher)
+	if header.Version > 1 {
+		fmt.Printf("chunk size: %d\n", header.ChunkSize)
+	}
+	fmt.Printf("chunks:     %d\n", description.Chunks)
+	fmt.Printf("header:     %d bytes\n", description.HeaderSize)
+	fmt.Printf("payload:    %d bytes, %d bytes of plaintext\n", description.PayloadSize, description.PlaintextSize)
+
+	if len(header.Metadata) > 0 {
+		var metadata fileformat.Metadata
+		if err := json.Unmarshal(header.Metadata, &metadata); err == nil {
+			fmt.Printf("name:       %s\n", metadata.Name)
+			fmt.Printf("mode:       %s\n", metadata.Mode)
+			fmt.Printf("modified:   %s\n", metadata.ModTime.Format(time.RFC3339))
+		}
+	}
+
+	fmt.Printf("recipients: %d\n", len(header.Stanzas))
+	for index, stanza := range header.Stanzas {
+		fmt.Printf("  %d\t%s\t%s\n", index, stanza.Type, describeStanza(stanza))
+	}
+
+	// Without the file key none of the above can be authenticated.
+	fmt.Fprintln(os.Stderr, "the header is not authenticated until the file is verified or decrypted")
+
+	return nil
+}
+
+// checkFile authenticates every chunk of an encrypted file and discards the
+// plaintext.
+func checkFile(inPath string, identities []fileformat.Identity) (int64, error) {
+	input, err := openInput(inPath)
+	if err != nil {
+		return 0, err
+	}
+	defer input.Close()
+
+	raw, _, err := armor.Detect(input)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := fileformat.NewReader(raw, identities...)
+	if err != nil {
+		return 0, err
+	}
+
+	checked, err := io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		return checked, err
+	}
+
+	if metadata := reader.Metadata(); metadata != nil && checked != metadata.Size {
+		return checked, fmt.Errorf("decrypted [%d] bytes but the header records [%d]", checked, metadata.Size)
+	}
+
+	return checked, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"util.tim/encrypto/core/armor"
+)
+
+const stdio = "-"
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == stdio {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+// withOutput hands write a destination for path. Regular files are written
+// to a temporary file next to path and only renamed over it once write
+// succeeds, so a failed run neither leaves a partial result behind nor
+// destroys a file that was already there.
+func withOutput(path string, perm os.FileMode, write func(io.Writer) error) error {
+	if path == stdio {
+		return write(os.Stdout)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && !info.Mode().IsRegular():
+		// Devices and pipes, such as /dev/null, can not be replaced.
+		return writeDirectly(path, perm, write)
+	case err == nil:
+		perm = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".encrypto-")
+	if err != nil {
+		return err
+	}
+
+	err = tmp.Chmod(perm)
+	if err == nil {
+		err = write(tmp)
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+func writeDirectly(path string, perm os.FileMode, write func(io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func withArmor(output io.Writer, useArmor bool, write func(io.Writer) error) error {
+	if !useArmor {
+		return write(output)
+	}
+
+	encoder := armor.NewEncoder(output)
+	if err := write(encoder); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"util.tim/encrypto/adapters/asymetric/keyformat"
+)
+
+func validateKeyFormat(format string) error {
+	for _, known := range keyformat.Formats {
+		if format == known {
+			return nil
+		}
+	}
+
+	return newUsageError("unknown key format [%s], expected one of %s", format, strings.Join(keyformat.Formats, ", "))
+}
+
+// publicFormat is the format that goes with a private key format when both
+// halves are written, since PKCS8 only holds private keys.
+func publicFormat(format string) string {
+	if format == keyformat.FormatPKCS8 {
+		return keyformat.FormatPKIX
+	}
+
+	return format
+}
+
+func readKey(inPath string) (keyformat.Key, error) {
+	input, err := openInput(inPath)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return keyformat.Parse(data)
+}
+
+func writeKey(outPath string, perm os.FileMode, key keyformat.Key, format string) error {
+	encoded, err := key.Encode(format)
+	if err != nil {
+		return err
+	}
+
+	return withOutput(outPath, perm, func(output io.Writer) error {
+		_, err := output.Write(encoded)
+		return err
+	})
+}
+
+func printFingerprints(output io.Writer, key keyformat.Key) {
+	fmt.Fprintf(output, "%s\t%d\t%s", key.Type(), key.Bits(), key.Fingerprint())
+	if sshFingerprint := key.SSHFingerprint(); sshFingerprint != "" {
+		fmt.Fprintf(output, "\tssh %s", sshFingerprint)
+	}
+	fmt.Fprintln(output)
+}
+
+func runKeygen(args []string) error {
+	flags := newFlagSet("keygen", "")
+	keyType := flags.String("type", keyformat.TypeEd25519, "the `type` of key: "+strings.Join(keyformat.Types, ", "))
+	bits := flags.Int("bits", 0, fmt.Sprintf("the RSA key size in bits, [%d] by default", keyformat.DefaultRSABits))
+	format := flags.String("format", keyformat.FormatPEM, "write the key as `format`: "+strings.Join(keyformat.Formats, ", "))
+	outPath := flags.String("o", stdio, "write the private key to `file` and the public key to file.pub")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return newUsageError("expected no arguments, received [%d]", flags.NArg())
+	}
+
+	if err := validateKeyFormat(*format); err != nil {
+		return err
+	}
+
+	if *format == keyformat.FormatPKIX {
+		return newUsageError("PKIX holds public keys only, use pem or pkcs8 for the private key")
+	}
+
+	key, err := keyformat.Generate(*keyType, *bits)
+	if err != nil {
+		return err
+	}
+
+	// Check the formats before anything is written, so an unsupported
+	// combination such as an X25519 key in OpenSSH format leaves no files.
+	if _, err = key.Public().Encode(publicFormat(*format)); err != nil {
+		return err
+	}
+
+	if err = writeKey(*outPath, 0600, key, *format); err != nil {
+		return err
+	}
+
+	if *outPath != stdio {
+		if err = writeKey(*outPath+".pub", 0644, key.Public(), publicFormat(*format)); err != nil {
+			return err
+		}
+	}
+
+	printFingerprints(os.Stderr, key)
+
+	return nil
+}
+
+func runConvert(args []string) error {
+	flags := newFlagSet("convert", "[INPUT]")
+	format := flags.String("to", keyformat.FormatPEM, "convert the key to `format`: "+strings.Join(keyformat.Formats, ", "))
+	public := flags.Bool("public", false, "write only the public half of a private key")
+	outPath := flags.String("o", stdio, "output `file`")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if err = validateKeyFormat(*format); err != nil {
+		return err
+	}
+
+	key, err := readKey(inPath)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0600)
+	if *public || !key.IsPrivate() {
+		key = key.Public()
+		perm = 0644
+	}
+
+	return writeKey(*outPath, perm, key, *format)
+}
+
+func runFingerprint(args []string) error {
+	flags := newFlagSet("fingerprint", "[INPUT]")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	key, err := readKey(inPath)
+	if err != nil {
+		return err
+	}
+
+	printFingerprints(os.Stdout, key)
+
+	return nil
+}
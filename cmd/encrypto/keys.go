@@ -0,0 +1,233 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"util.tim/encrypto/adapters/asymetric/local"
+	"util.tim/encrypto/adapters/asymetric/remote"
+	"util.tim/encrypto/adapters/symmetric/passphrase"
+	"util.tim/encrypto/core/fileformat"
+)
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+type intList []int
+
+func (list *intList) String() string {
+	return fmt.Sprint(*list)
+}
+
+func (list *intList) Set(value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+
+	*list = append(*list, parsed)
+	return nil
+}
+
+func readKeyFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(contents), nil
+}
+
+func loadRecipients(paths []string) ([]fileformat.Recipient, error) {
+	recipients := []fileformat.Recipient{}
+
+	for _, path := range paths {
+		pemString, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		recipient, err := remote.NewRecipient(pemString)
+		if err != nil {
+			return nil, fmt.Errorf("could not load recipient [%s]: %w", path, err)
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+func loadIdentities(paths []string) ([]fileformat.Identity, error) {
+	identities := []fileformat.Identity{}
+
+	for _, path := range paths {
+		pemString, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := local.NewIdentity(pemString)
+		if err != nil {
+			return nil, fmt.Errorf("could not load identity [%s]: %w", path, err)
+		}
+
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+type recipientOptions struct {
+	passphraseFile *string
+	usePassphrase  *bool
+	useAgent       *bool
+	paths          stringList
+	sshKeys        stringList
+	keyNames       stringList
+	keystore       *keystoreOptions
+	shares         *shareOptions
+}
+
+func addRecipientFlags(flags *flag.FlagSet) *recipientOptions {
+	options := &recipientOptions{
+		passphraseFile: flags.String("passphrase-file", "", "read the passphrase from `file` instead of prompting"),
+		usePassphrase:  flags.Bool("passphrase", false, "also encrypt to a passphrase when recipients are given"),
+		useAgent:       flags.Bool("ssh-agent", false, "encrypt to the keys in ssh-agent, so the agent can decrypt with -ssh-agent"),
+		keystore:       addKeystoreFlags(flags),
+		shares:         addShareFlags(flags),
+	}
+	flags.Var(&options.paths, "recipient", "encrypt to the RSA public key in PEM `file`, may be repeated")
+	flags.Var(&options.sshKeys, "ssh-recipient", "encrypt to the ssh-rsa and ssh-ed25519 keys in authorized_keys `file`, or to a key given inline, may be repeated")
+	flags.Var(&options.keyNames, "recipient-key", "encrypt to the keystore key called `name`, may be repeated")
+
+	return options
+}
+
+func (options *recipientOptions) recipients() ([]fileformat.Recipient, error) {
+	recipients, err := loadRecipients(options.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	sshRecipients, err := loadSSHRecipients(options.sshKeys)
+	if err != nil {
+		return nil, err
+	}
+	recipients = append(recipients, sshRecipients...)
+
+	if *options.useAgent {
+		fromAgent, err := agentRecipients()
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, fromAgent...)
+	}
+
+	named, err := options.keystore.recipients(options.keyNames)
+	if err != nil {
+		return nil, err
+	}
+	recipients = append(recipients, named...)
+
+	shared, err := options.shares.recipients()
+	if err != nil {
+		return nil, err
+	}
+	recipients = append(recipients, shared...)
+
+	if len(recipients) == 0 || *options.usePassphrase || *options.passphraseFile != "" {
+		secret, err := readPassphrase(*options.passphraseFile, "Passphrase: ", true)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, passphrase.NewRecipient(secret, passphrase.DefaultParams()))
+	}
+
+	return recipients, nil
+}
+
+type identityOptions struct {
+	passphraseFile *string
+	useAgent       *bool
+	paths          stringList
+	sshPaths       stringList
+	keyNames       stringList
+	sharePaths     stringList
+	keystore       *keystoreOptions
+}
+
+func addIdentityFlags(flags *flag.FlagSet) *identityOptions {
+	options := &identityOptions{
+		passphraseFile: flags.String("passphrase-file", "", "read the passphrase from `file` instead of prompting"),
+		useAgent:       flags.Bool("ssh-agent", false, "decrypt with the keys in ssh-agent, for files encrypted with -ssh-agent"),
+		keystore:       addKeystoreFlags(flags),
+	}
+	flags.Var(&options.paths, "identity", "decrypt with the RSA private key in PEM `file`, may be repeated")
+	flags.Var(&options.sshPaths, "ssh-identity", "decrypt with the OpenSSH private key in `file`, may be repeated")
+	flags.Var(&options.keyNames, "identity-key", "decrypt with the keystore key called `name`, may be repeated")
+	flags.Var(&options.sharePaths, "share", "decrypt with the key shares in `file`, may be repeated")
+
+	return options
+}
+
+func (options *identityOptions) given() bool {
+	return *options.passphraseFile != "" || *options.useAgent || len(options.paths) > 0 || len(options.sshPaths) > 0 ||
+		len(options.keyNames) > 0 || len(options.sharePaths) > 0
+}
+
+func (options *identityOptions) identities() ([]fileformat.Identity, error) {
+	identities, err := loadIdentities(options.paths)
+	if err != nil {
+		return nil, err
+	}
+
+	named, err := options.keystore.identities(options.keyNames)
+	if err != nil {
+		return nil, err
+	}
+	identities = append(identities, named...)
+
+	sshIdentities, err := loadSSHIdentities(options.sshPaths)
+	if err != nil {
+		return nil, err
+	}
+	identities = append(identities, sshIdentities...)
+
+	shared, err := loadShares(options.sharePaths)
+	if err != nil {
+		return nil, err
+	}
+	identities = append(identities, shared...)
+
+	if *options.useAgent {
+		identity, err := agentIdentity()
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	if len(identities) == 0 || *options.passphraseFile != "" {
+		secret, err := readPassphrase(*options.passphraseFile, "Passphrase: ", false)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, passphrase.NewIdentity(secret))
+	}
+
+	return identities, nil
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"util.tim/encrypto/adapters/asymetric/keyformat"
+	"util.tim/encrypto/adapters/asymetric/local"
+	"util.tim/encrypto/adapters/asymetric/remote"
+	"util.tim/encrypto/adapters/symmetric/passphrase"
+	"util.tim/encrypto/adapters/symmetric/secretkey"
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/keystore"
+)
+
+func defaultKeystorePath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "encrypto.keystore"
+	}
+
+	return filepath.Join(configDir, "encrypto", "keystore")
+}
+
+type keystoreOptions struct {
+	path           *string
+	passphraseFile *string
+	passphrase     string
+	loaded         keystore.Keystore
+}
+
+func addKeystoreFlags(flags *flag.FlagSet) *keystoreOptions {
+	return &keystoreOptions{
+		path:           flags.String("keystore", defaultKeystorePath(), "use the keystore at `path`"),
+		passphraseFile: flags.String("keystore-passphrase-file", "", "read the keystore passphrase from `file` instead of prompting"),
+	}
+}
+
+func (options *keystoreOptions) readPassphrase(confirm bool) (string, error) {
+	if options.passphrase == "" {
+		secret, err := readPassphrase(*options.passphraseFile, "Keystore passphrase: ", confirm)
+		if err != nil {
+			return "", err
+		}
+
+		options.passphrase = secret
+	}
+
+	return options.passphrase, nil
+}
+
+// load opens the keystore, or starts an empty one when create is set and
+// there is no keystore yet.
+func (options *keystoreOptions) load(create bool) (keystore.Keystore, error) {
+	file, err := os.Open(*options.path)
+	if os.IsNotExist(err) && create {
+		if _, err = options.readPassphrase(true); err != nil {
+			return nil, err
+		}
+
+		return keystore.New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	secret, err := options.readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.Load(file, passphrase.NewIdentity(secret))
+}
+
+func (options *keystoreOptions) save(store keystore.Keystore) error {
+	secret, err := options.readPassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(*options.path), 0700); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(*options.path); os.IsNotExist(err) {
+		if err = createPrivateFile(*options.path); err != nil {
+			return err
+		}
+	}
+
+	return rewriteFile(*options.path, func(dst io.Writer, src io.Reader) error {
+		return keystore.Save(dst, store, passphrase.NewRecipient(secret, passphrase.DefaultParams()))
+	})
+}
+
+func createPrivateFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (options *keystoreOptions) get(name string) (keystore.Entry, error) {
+	if options.loaded == nil {
+		store, err := options.load(false)
+		if err != nil {
+			return keystore.Entry{}, err
+		}
+
+		options.loaded = store
+	}
+
+	store := options.loaded
+
+	entry, found := store.Get(name)
+	if !found {
+		return keystore.Entry{}, fmt.Errorf("there is no key named [%s] in the keystore", name)
+	}
+
+	return entry, nil
+}
+
+func entryRecipient(entry keystore.Entry) (fileformat.Recipient, error) {
+	switch entry.Type {
+	case keystore.TypeSecret:
+		key, err := base64.StdEncoding.DecodeString(entry.Material)
+		if err != nil {
+			return nil, err
+		}
+
+		return secretkey.NewRecipient(key)
+	case keystore.TypeRSA:
+		publicKey, err := local.PublicKeyPEM(entry.Material)
+		if err != nil {
+			return nil, err
+		}
+
+		return remote.NewRecipient(publicKey)
+	case keystore.TypeRSAPublic:
+		return remote.NewRecipient(entry.Material)
+	default:
+		return nil, fmt.Errorf("[%s] is a [%s] key, which cannot be encrypted to", entry.Name, entry.Type)
+	}
+}
+
+func entryIdentity(entry keystore.Entry) (fileformat.Identity, error) {
+	switch entry.Type {
+	case keystore.TypeSecret:
+		key, err := base64.StdEncoding.DecodeString(entry.Material)
+		if err != nil {
+			return nil, err
+		}
+
+		return secretkey.NewIdentity(key)
+	case keystore.TypeRSA:
+		return local.NewIdentity(entry.Material)
+	default:
+		return nil, fmt.Errorf("[%s] is a [%s] key, which cannot decrypt", entry.Name, entry.Type)
+	}
+}
+
+func (options *keystoreOptions) recipients(names []string) ([]fileformat.Recipient, error) {
+	recipients := []fileformat.Recipient{}
+
+	for _, name := range names {
+		entry, err := options.get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		recipient, err := entryRecipient(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
+func (options *keystoreOptions) identities(names []string) ([]fileformat.Identity, error) {
+	identities := []fileformat.Identity{}
+
+	for _, name := range names {
+		entry, err := options.get(name)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := entryIdentity(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+func classifyKey(pemString string) (string, error) {
+	if signer, err := local.NewSignerFromPEM(pemString); err == nil {
+		if signer.Algorithm() == asymetric.SignatureRSAPSS {
+			return keystore.TypeRSA, nil
+		}
+
+		return keystore.TypeEd25519, nil
+	}
+
+	if verifier, err := remote.NewVerifierFromPEM(pemString); err == nil {
+		if verifier.Algorithm() == asymetric.SignatureRSAPSS {
+			return keystore.TypeRSAPublic, nil
+		}
+
+		return keystore.TypeEdPublic, nil
+	}
+
+	return "", errors.New("expected an RSA or Ed25519 key in PEM format")
+}
+
+func entryFingerprint(entry keystore.Entry) string {
+	switch entry.Type {
+	case keystore.TypeSecret:
+		key, err := base64.StdEncoding.DecodeString(entry.Material)
+		if err != nil {
+			return ""
+		}
+
+		return secretkey.Id(key)
+	case keystore.TypeRSA, keystore.TypeEd25519:
+		signer, err := local.NewSignerFromPEM(entry.Material)
+		if err != nil {
+			return ""
+		}
+
+		return asymetric.Fingerprint(signer.PublicKeyBytes())
+	default:
+		verifier, err := remote.NewVerifierFromPEM(entry.Material)
+		if err != nil {
+			return ""
+		}
+
+		return asymetric.Fingerprint(verifier.PublicKeyBytes())
+	}
+}
+
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of add, list, export or delete")
+	}
+
+	switch args[0] {
+	case "add":
+		return runKeysAdd(args[1:])
+	case "list":
+		return runKeysList(args[1:])
+	case "export":
+		return runKeysExport(args[1:])
+	case "delete":
+		return runKeysDelete(args[1:])
+	default:
+		return newUsageError("unknown keys command [%s], expected one of add, list, export or delete", args[0])
+	}
+}
+
+func newKeyEntry(name string, generate string, keyPath string) (keystore.Entry, error) {
+	entry := keystore.Entry{
+		Name:    name,
+		Created: time.Now().UTC(),
+	}
+
+	var err error
+	switch {
+	case generate != "" && keyPath != "":
+		return entry, newUsageError("use either -generate or -file")
+	case generate == keystore.TypeSecret:
+		key, err := secretkey.Generate()
+		if err != nil {
+			return entry, err
+		}
+
+		entry.Type = keystore.TypeSecret
+		entry.Material = base64.StdEncoding.EncodeToString(key)
+		return entry, nil
+	case generate == keystore.TypeRSA:
+		entry.Type = keystore.TypeRSA
+		entry.Material, err = local.GenerateRSA(3072)
+		return entry, err
+	case generate == keystore.TypeEd25519:
+		entry.Type = keystore.TypeEd25519
+		entry.Material, err = local.GenerateEd25519()
+		return entry, err
+	case generate != "":
+		return entry, newUsageError("unknown key type [%s], expected secret, rsa or ed25519", generate)
+	case keyPath != "":
+		key, err := readKey(keyPath)
+		if err != nil {
+			return entry, err
+		}
+
+		// Keys are stored as PEM whatever format they arrive in.
+		material, err := key.Encode(keyformat.FormatPEM)
+		if err != nil {
+			return entry, err
+		}
+
+		entry.Material = string(material)
+		entry.Type, err = classifyKey(entry.Material)
+		return entry, err
+	default:
+		return entry, newUsageError("use -generate to create a key or -file to import one")
+	}
+}
+
+func runKeysAdd(args []string) error {
+	flags := newFlagSet("keys add", "NAME")
+	generate := flags.String("generate", "", "generate a new `type` of key: secret, rsa or ed25519")
+	keyPath := flags.String("file", "", "import the RSA or Ed25519 key in PEM, JWK or OpenSSH `file`")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	entry, err := newKeyEntry(name, *generate, *keyPath)
+	if err != nil {
+		return err
+	}
+
+	store, err := keystoreOptions.load(true)
+	if err != nil {
+		return err
+	}
+
+	if err = store.Add(entry); err != nil {
+		return err
+	}
+
+	return keystoreOptions.save(store)
+}
+
+func runKeysList(args []string) error {
+	flags := newFlagSet("keys list", "")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	store, err := keystoreOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range store.Entries() {
+		fmt.Printf("%s\t%s\t%s\t%s\n", entry.Name, entry.Type, entryFingerprint(entry), entry.Created.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func runKeysExport(args []string) error {
+	flags := newFlagSet("keys export", "NAME")
+	outPath := flags.String("o", stdio, "output `file`")
+	public := flags.Bool("public", false, "export only the public half of a keypair")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	entry, err := keystoreOptions.get(name)
+	if err != nil {
+		return err
+	}
+
+	material := entry.Material
+	if *public {
+		switch entry.Type {
+		case keystore.TypeRSA, keystore.TypeEd25519:
+			if material, err = local.PublicKeyPEM(entry.Material); err != nil {
+				return err
+			}
+		case keystore.TypeRSAPublic, keystore.TypeEdPublic:
+		default:
+			return fmt.Errorf("[%s] is a [%s] key, which has no public half", entry.Name, entry.Type)
+		}
+	} else if entry.Type == keystore.TypeSecret {
+		material += "\n"
+	}
+
+	return withOutput(*outPath, 0600, func(output io.Writer) error {
+		_, err := io.WriteString(output, material)
+		return err
+	})
+}
+
+func runKeysDelete(args []string) error {
+	flags := newFlagSet("keys delete", "NAME")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	store, err := keystoreOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	if err = store.Delete(name); err != nil {
+		return err
+	}
+
+	return keystoreOptions.save(store)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+type usageError struct {
+	message string
+}
+
+func (err usageError) Error() string {
+	return err.message
+}
+
+func newUsageError(format string, args ...interface{}) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+func commands() []command {
+	return []command{
+		{"encrypt", "encrypt a file to a passphrase or public keys", runEncrypt},
+		{"decrypt", "decrypt a file", runDecrypt},
+		{"edit", "decrypt a file into a private temporary copy, edit it and encrypt it again", runEdit},
+		{"doc", "encrypt, decrypt or edit the values of a JSON or YAML document", runDocument},
+		{"exec", "run a command with the variables from encrypted env files", runExec},
+		{"archive", "create, list or extract an encrypted directory archive", runArchive},
+		{"backup", "initialise a deduplicating encrypted backup repository, add a snapshot or list them", runBackup},
+		{"restore", "restore a snapshot, or some of its files, from a backup repository", runRestore},
+		{"recipients", "list, add or remove the recipients of an encrypted file", runRecipients},
+		{"keys", "add, list, export or delete keys in the encrypted keystore", runKeys},
+		{"keygen", "generate an RSA, Ed25519 or X25519 keypair", runKeygen},
+		{"convert", "convert a key between PEM, JWK and OpenSSH formats", runConvert},
+		{"fingerprint", "print the type, size and fingerprints of a key", runFingerprint},
+		{"git", "set up and run git filters that keep matching files encrypted in a repository", runGit},
+		{"sign", "write a detached signature for a file", runSign},
+		{"inspect", "print the header of an encrypted file without decrypting it", runInspect},
+		{"verify", "check a detached signature or authenticate every chunk of an encrypted file", runVerify},
+		{"paper", "print a key as words and a QR code to keep on paper, or restore it from the words", runPaper},
+		{"otp", "print or check one time passwords, create otpauth keys and keep accounts in an encrypted vault", runOtp},
+	}
+}
+
+func newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: encrypto %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		// The flag package has already reported the problem and the usage.
+		return usageError{}
+	}
+
+	return err
+}
+
+func singleInput(flags *flag.FlagSet) (string, error) {
+	switch flags.NArg() {
+	case 0:
+		return stdio, nil
+	case 1:
+		return flags.Arg(0), nil
+	default:
+		return "", newUsageError("expected at most one input, received [%d]", flags.NArg())
+	}
+}
+
+func singleFile(flags *flag.FlagSet) (string, error) {
+	if flags.NArg() != 1 {
+		return "", newUsageError("expected exactly one file, received [%d]", flags.NArg())
+	}
+
+	return flags.Arg(0), nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: encrypto <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, command := range commands() {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", command.name, command.summary)
+	}
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Use '-' for a file argument to read stdin or write stdout.")
+}
+
+func exitCode(name string, err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	var status exitStatus
+	if errors.As(err, &status) {
+		return int(status)
+	}
+
+	var usageErr usageError
+	if errors.As(err, &usageErr) {
+		if usageErr.message != "" {
+			fmt.Fprintf(os.Stderr, "encrypto %s: %s\n", name, usageErr.message)
+		}
+		return 2
+	}
+
+	fmt.Fprintf(os.Stderr, "encrypto %s: %s\n", name, err)
+	return 1
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, command := range commands() {
+		if command.name == name {
+			os.Exit(exitCode(name, command.run(os.Args[2:])))
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "encrypto: unknown command [%s]\n", name)
+	usage()
+	os.Exit(2)
+}
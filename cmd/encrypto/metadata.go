@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+func metadataFor(path string) (*fileformat.Metadata, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	return &fileformat.Metadata{
+		Name:    filepath.Base(path),
+		Size:    info.Size(),
+		Mode:    info.Mode().Perm(),
+		ModTime: info.ModTime(),
+	}, nil
+}
+
+// restorePath only accepts a bare file name, so a crafted header cannot make
+// decrypt write outside of the current directory.
+func restorePath(metadata *fileformat.Metadata) (string, error) {
+	if metadata == nil {
+		return "", fmt.Errorf("the file has no metadata to restore")
+	}
+
+	name := metadata.Name
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name || strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("refusing to restore unsafe file name [%s]", name)
+	}
+
+	if _, err := os.Lstat(name); err == nil {
+		return "", fmt.Errorf("[%s] already exists", name)
+	}
+
+	return name, nil
+}
+
+func restoreAttributes(path string, metadata *fileformat.Metadata) error {
+	if err := os.Chmod(path, metadata.Mode.Perm()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(path, metadata.ModTime, metadata.ModTime)
+}
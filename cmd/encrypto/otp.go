@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"util.tim/encrypto/core/otp"
+	"util.tim/encrypto/core/qr"
+)
+
+// runOtp works on a bare secret or URI unless a subcommand names what to do,
+// and the add, list, code, rename and delete subcommands use the vault.
+func runOtp(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "new":
+			return runOtpNew(args[1:])
+		case "qr":
+			return runOtpQR(args[1:])
+		case "add":
+			return runOtpAdd(args[1:])
+		case "list":
+			return runOtpList(args[1:])
+		case "code":
+			return runOtpAccountCode(args[1:])
+		case "rename":
+			return runOtpRename(args[1:])
+		case "delete":
+			return runOtpDelete(args[1:])
+		}
+	}
+
+	return runOtpCode(args)
+}
+
+type otpParamsOptions struct {
+	hash   *string
+	digits *int
+	period *time.Duration
+}
+
+func addOtpParamsFlags(flags *flag.FlagSet) *otpParamsOptions {
+	return &otpParamsOptions{
+		hash:   flags.String("hash", otp.HashSHA1, "HMAC `hash`, one of "+strings.Join(otp.Hashes, ", ")),
+		digits: flags.Int("digits", otp.DefaultDigits, "number of digits in the code"),
+		period: flags.Duration("period", otp.DefaultPeriod, "how long each TOTP code lasts"),
+	}
+}
+
+func (options *otpParamsOptions) params() otp.Params {
+	return otp.Params{Hash: *options.hash, Digits: *options.digits, Period: *options.period}
+}
+
+// loadOtpKey reads a whole otpauth URI, or a bare secret with the
+// parameters from the flags. Bare secrets are Base32, as sites show them,
+// unless raw is set.
+func loadOtpKey(uriFile string, secretFile string, raw bool, params otp.Params) (otp.Key, error) {
+	if uriFile != "" {
+		uri, err := readSecretFile(uriFile)
+		if err != nil {
+			return otp.Key{}, err
+		}
+
+		return otp.ParseURI(uri)
+	}
+
+	var secret string
+	var err error
+	if secretFile != "" {
+		secret, err = readSecretFile(secretFile)
+	} else {
+		secret, err = promptSecret("Secret: ")
+	}
+	if err != nil {
+		return otp.Key{}, err
+	}
+
+	key := otp.Key{Type: otp.TypeTOTP, Secret: []byte(secret), Params: params}
+	if !raw {
+		if key.Secret, err = otp.DecodeSecret(secret); err != nil {
+			return otp.Key{}, fmt.Errorf("%w, use -raw for a secret that is not Base32", err)
+		}
+	}
+
+	return key, nil
+}
+
+func runOtpCode(args []string) error {
+	flags := newFlagSet("otp", "")
+	uriFile := flags.String("uri-file", "", "read an otpauth URI, with its secret and parameters, from `file`")
+	secretFile := flags.String("secret-file", "", "read the Base32 shared secret from `file` instead of prompting")
+	raw := flags.Bool("raw", false, "use the secret's bytes as they are instead of decoding Base32")
+	paramsOptions := addOtpParamsFlags(flags)
+	counter := flags.Int64("counter", -1, "print the HOTP code for this counter instead of the current TOTP code")
+	verifyCode := flags.String("verify", "", "check `code` instead of printing one")
+	skew := flags.Int("skew", 1, "with -verify, also accept codes this many periods, or HOTP counters, away")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return newUsageError("unexpected arguments %v", flags.Args())
+	}
+
+	if *skew < 0 || *skew > otp.MaxSkew {
+		return newUsageError("-skew must be between [0] and [%d], received [%d]", otp.MaxSkew, *skew)
+	}
+
+	key, err := loadOtpKey(*uriFile, *secretFile, *raw, paramsOptions.params())
+	if err != nil {
+		return err
+	}
+
+	if key.Type == otp.TypeHOTP && *counter < 0 {
+		*counter = int64(key.Counter)
+	}
+
+	generator, err := key.Generator()
+	if err != nil {
+		return err
+	}
+
+	if *verifyCode != "" {
+		found := false
+		if *counter >= 0 {
+			var matched uint64
+			if matched, found = generator.VerifyHOTP(*verifyCode, uint64(*counter), *skew); found {
+				fmt.Fprintf(os.Stderr, "code matches counter %d\n", matched)
+			}
+		} else {
+			found = generator.Verify(*verifyCode, time.Now(), *skew)
+		}
+
+		if !found {
+			return errors.New("the code does not match")
+		}
+
+		return nil
+	}
+
+	if *counter >= 0 {
+		fmt.Println(generator.HOTP(uint64(*counter)))
+	} else {
+		fmt.Println(generator.TOTP(time.Now()))
+	}
+
+	return nil
+}
+
+// writeEnrollment shows a key the ways authenticator apps take it: a QR
+// code to scan and the secret in groups of four to type in.
+func writeEnrollment(dst io.Writer, key otp.Key, invert bool) error {
+	code, err := encodeQR(key.URI())
+	if err != nil {
+		return err
+	}
+
+	if err = qr.WriteTerminal(dst, code, invert); err != nil {
+		return err
+	}
+
+	secret := otp.EncodeSecret(key.Secret)
+	groups := []string{}
+	for start := 0; start < len(secret); start += 4 {
+		end := start + 4
+		if end > len(secret) {
+			end = len(secret)
+		}
+		groups = append(groups, secret[start:end])
+	}
+
+	name := strings.TrimSpace(key.Issuer + " " + key.Account)
+	_, err = fmt.Fprintf(dst, "\n%s, %s, %d digits\nsecret %s\n", name, strings.ToUpper(key.Params.Hash), key.Params.Digits,
+		strings.Join(groups, " "))
+	return err
+}
+
+func runOtpNew(args []string) error {
+	flags := newFlagSet("otp new", "")
+	issuer := flags.String("issuer", "", "the `name` of the service the code is for")
+	account := flags.String("account", "", "the `name` of the account, usually a user name or email address")
+	keyType := flags.String("type", otp.TypeTOTP, "totp or hotp")
+	paramsOptions := addOtpParamsFlags(flags)
+	counter := flags.Uint64("counter", 0, "the first HOTP counter")
+	secretSize := flags.Int("secret-size", otp.DefaultSecretSize, "the secret size in `bytes`")
+	outPath := flags.String("o", stdio, "write the otpauth URI to `file`")
+	showQR := flags.Bool("qr", true, "print a QR code of the URI to scan with an authenticator app")
+	invert := flags.Bool("invert", false, "draw the QR code for dark text on a light background")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return newUsageError("unexpected arguments %v", flags.Args())
+	}
+
+	if *account == "" {
+		return newUsageError("-account is required")
+	}
+
+	if *keyType != otp.TypeTOTP && *keyType != otp.TypeHOTP {
+		return newUsageError("unknown OTP type [%s], expected totp or hotp", *keyType)
+	}
+
+	secret, err := otp.GenerateSecret(*secretSize)
+	if err != nil {
+		return err
+	}
+
+	key := otp.Key{
+		Type:    *keyType,
+		Issuer:  *issuer,
+		Account: *account,
+		Secret:  secret,
+		Params:  paramsOptions.params(),
+		Counter: *counter,
+	}
+
+	if _, err = key.Generator(); err != nil {
+		return err
+	}
+
+	err = withOutput(*outPath, 0600, func(output io.Writer) error {
+		_, err := fmt.Fprintln(output, key.URI())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if *showQR {
+		return writeEnrollment(os.Stdout, key, *invert)
+	}
+
+	return nil
+}
+
+func runOtpQR(args []string) error {
+	flags := newFlagSet("otp qr", "[URI-FILE]")
+	invert := flags.Bool("invert", false, "draw the QR code for dark text on a light background")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	uri, err := ioutil.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	key, err := otp.ParseURI(string(uri))
+	if err != nil {
+		return err
+	}
+
+	return writeEnrollment(os.Stdout, key, *invert)
+}
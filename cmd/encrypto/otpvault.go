@@ -0,0 +1,331 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/term"
+	"util.tim/encrypto/adapters/symmetric/passphrase"
+	"util.tim/encrypto/core/authenticator"
+	"util.tim/encrypto/core/otp"
+)
+
+func defaultVaultPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "encrypto.otp-vault"
+	}
+
+	return filepath.Join(configDir, "encrypto", "otp-vault")
+}
+
+type vaultOptions struct {
+	path           *string
+	passphraseFile *string
+	passphrase     string
+}
+
+func addVaultFlags(flags *flag.FlagSet) *vaultOptions {
+	return &vaultOptions{
+		path:           flags.String("vault", defaultVaultPath(), "use the authenticator vault at `path`"),
+		passphraseFile: flags.String("vault-passphrase-file", "", "read the vault passphrase from `file` instead of prompting"),
+	}
+}
+
+func (options *vaultOptions) readPassphrase(confirm bool) (string, error) {
+	if options.passphrase == "" {
+		secret, err := readPassphrase(*options.passphraseFile, "Vault passphrase: ", confirm)
+		if err != nil {
+			return "", err
+		}
+
+		options.passphrase = secret
+	}
+
+	return options.passphrase, nil
+}
+
+// load opens the vault, or starts an empty one when create is set and there
+// is no vault yet.
+func (options *vaultOptions) load(create bool) (authenticator.Vault, error) {
+	file, err := os.Open(*options.path)
+	if os.IsNotExist(err) && create {
+		if _, err = options.readPassphrase(true); err != nil {
+			return nil, err
+		}
+
+		return authenticator.New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	secret, err := options.readPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticator.Load(file, passphrase.NewIdentity(secret))
+}
+
+// lock keeps other encrypto processes from loading the vault until release
+// is called, so two of them can not hand out codes for the same HOTP
+// counter or lose each other's changes. The passphrase is asked for first,
+// so an interrupted prompt does not leave the lock file behind.
+func (options *vaultOptions) lock(create bool) (release func(), err error) {
+	_, err = os.Stat(*options.path)
+	if err != nil && !(os.IsNotExist(err) && create) {
+		return nil, err
+	}
+
+	if _, err = options.readPassphrase(err != nil); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(*options.path), 0700); err != nil {
+		return nil, err
+	}
+
+	lockPath := *options.path + ".lock"
+	if err = createPrivateFile(lockPath); os.IsExist(err) {
+		return nil, fmt.Errorf("the vault is in use by another encrypto, remove [%s] if none is running", lockPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() { os.Remove(lockPath) }, nil
+}
+
+func (options *vaultOptions) save(vault authenticator.Vault) error {
+	secret, err := options.readPassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(*options.path), 0700); err != nil {
+		return err
+	}
+
+	if _, err = os.Stat(*options.path); os.IsNotExist(err) {
+		if err = createPrivateFile(*options.path); err != nil {
+			return err
+		}
+	}
+
+	return rewriteFile(*options.path, func(dst io.Writer, src io.Reader) error {
+		return authenticator.Save(dst, vault, passphrase.NewRecipient(secret, passphrase.DefaultParams()))
+	})
+}
+
+// readURI keeps the URI off the screen when it is typed, since it holds
+// the secret.
+func readURI(uriFile string) (string, error) {
+	if uriFile != "" {
+		return readSecretFile(uriFile)
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return promptSecret("otpauth URI: ")
+	}
+
+	contents, err := ioutil.ReadAll(os.Stdin)
+	return string(contents), err
+}
+
+func runOtpAdd(args []string) error {
+	flags := newFlagSet("otp add", "NAME")
+	uriFile := flags.String("uri-file", "", "read the otpauth URI from `file` instead of stdin")
+	vaultOptions := addVaultFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	uri, err := readURI(*uriFile)
+	if err != nil {
+		return err
+	}
+
+	key, err := otp.ParseURI(uri)
+	if err != nil {
+		return err
+	}
+
+	release, err := vaultOptions.lock(true)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	vault, err := vaultOptions.load(true)
+	if err != nil {
+		return err
+	}
+
+	if err = vault.Add(authenticator.Account{Name: name, URI: key.URI(), Added: time.Now().UTC()}); err != nil {
+		return err
+	}
+
+	return vaultOptions.save(vault)
+}
+
+func runOtpList(args []string) error {
+	flags := newFlagSet("otp list", "")
+	vaultOptions := addVaultFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 0 {
+		return newUsageError("unexpected arguments %v", flags.Args())
+	}
+
+	vault, err := vaultOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, account := range vault.Accounts() {
+		key, err := otp.ParseURI(account.URI)
+		if err != nil {
+			return fmt.Errorf("account [%s]: %w", account.Name, err)
+		}
+
+		label := key.Account
+		if key.Issuer != "" {
+			label = key.Issuer + ":" + key.Account
+		}
+
+		// Showing an HOTP code would use it up, so only the counter is
+		// listed and otp code hands out the next one.
+		if key.Type == otp.TypeHOTP {
+			fmt.Printf("%s\thotp\tcounter %d\t%s\n", account.Name, key.Counter, label)
+			continue
+		}
+
+		code, remaining, err := vault.Code(account.Name, now)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\t%s\t%2ds left\t%s\n", account.Name, code, int(remaining.Seconds()+0.5), label)
+	}
+
+	return nil
+}
+
+func runOtpAccountCode(args []string) error {
+	flags := newFlagSet("otp code", "NAME")
+	vaultOptions := addVaultFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	release, err := vaultOptions.lock(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	vault, err := vaultOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	code, remaining, err := vault.Code(name, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// The HOTP counter has moved on, and has to be stored before the code
+	// is shown so it is never handed out twice.
+	if remaining == 0 {
+		if err = vaultOptions.save(vault); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println(code)
+	return nil
+}
+
+func runOtpRename(args []string) error {
+	flags := newFlagSet("otp rename", "NAME NEW-NAME")
+	vaultOptions := addVaultFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return newUsageError("expected the current and the new name, received [%d] arguments", flags.NArg())
+	}
+
+	release, err := vaultOptions.lock(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	vault, err := vaultOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	if err = vault.Rename(flags.Arg(0), flags.Arg(1)); err != nil {
+		return err
+	}
+
+	return vaultOptions.save(vault)
+}
+
+func runOtpDelete(args []string) error {
+	flags := newFlagSet("otp delete", "NAME")
+	vaultOptions := addVaultFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	name, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	release, err := vaultOptions.lock(false)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	vault, err := vaultOptions.load(false)
+	if err != nil {
+		return err
+	}
+
+	if err = vault.Delete(name); err != nil {
+		return err
+	}
+
+	return vaultOptions.save(vault)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/term"
+	"util.tim/encrypto/adapters/asymetric/local"
+	"util.tim/encrypto/adapters/symmetric/secretkey"
+	"util.tim/encrypto/core/keystore"
+	"util.tim/encrypto/core/mnemonic"
+	"util.tim/encrypto/core/qr"
+)
+
+const pngScale = 8
+
+// paperKey is what goes on paper: words for keys that are a short random
+// value, and the PEM itself for RSA keys, which only fit in a QR code.
+type paperKey struct {
+	keyType string
+	words   []string
+	pem     string
+}
+
+func (key paperKey) qrContent() string {
+	if len(key.words) > 0 {
+		return strings.Join(key.words, " ")
+	}
+
+	return key.pem
+}
+
+func newPaperKey(keyType string, material string) (paperKey, error) {
+	key := paperKey{keyType: keyType}
+
+	var entropy []byte
+	var err error
+	switch keyType {
+	case keystore.TypeSecret:
+		entropy, err = base64.StdEncoding.DecodeString(strings.TrimSpace(material))
+		if err == nil && len(entropy) != secretkey.KeySize {
+			err = fmt.Errorf("expected a [%d] byte key, received [%d]", secretkey.KeySize, len(entropy))
+		}
+	case keystore.TypeEd25519:
+		entropy, err = local.Ed25519Seed(material)
+	case keystore.TypeRSA:
+		key.pem = material
+		return key, nil
+	default:
+		return key, fmt.Errorf("a [%s] key is public and needs no paper backup", keyType)
+	}
+	if err != nil {
+		return key, err
+	}
+
+	key.words, err = mnemonic.Encode(entropy)
+	return key, err
+}
+
+// readPaperKeyFile accepts a base64 secret key, as written by keys export
+// and git export-key, or a private key in PEM format.
+func readPaperKeyFile(path string) (paperKey, error) {
+	material, err := readKeyFile(path)
+	if err != nil {
+		return paperKey{}, err
+	}
+
+	if key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(material)); err == nil && len(key) == secretkey.KeySize {
+		return newPaperKey(keystore.TypeSecret, material)
+	}
+
+	keyType, err := classifyKey(material)
+	if err != nil {
+		return paperKey{}, err
+	}
+
+	return newPaperKey(keyType, material)
+}
+
+// encodeQR prefers medium error correction, which survives some wear on the
+// paper, and drops to low when the key would not fit otherwise.
+func encodeQR(content string) (qr.Code, error) {
+	code, err := qr.Encode([]byte(content), qr.Medium)
+	if err != nil {
+		return qr.Encode([]byte(content), qr.Low)
+	}
+
+	return code, nil
+}
+
+// writeWords numbers the words across each line, so reading the lines in
+// order, or pasting them into import, keeps the words in order.
+func writeWords(dst io.Writer, words []string) {
+	const columns = 4
+
+	for start := 0; start < len(words); start += columns {
+		line := ""
+		for index := start; index < start+columns && index < len(words); index++ {
+			line += fmt.Sprintf("%3d. %-10s", index+1, words[index])
+		}
+
+		fmt.Fprintln(dst, strings.TrimRight(line, " "))
+	}
+}
+
+func runPaper(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of export or import")
+	}
+
+	switch args[0] {
+	case "export":
+		return runPaperExport(args[1:])
+	case "import":
+		return runPaperImport(args[1:])
+	default:
+		return newUsageError("unknown paper command [%s], expected one of export or import", args[0])
+	}
+}
+
+func exportedPaperKey(args []string, keyPath string, keystoreOptions *keystoreOptions) (paperKey, error) {
+	if keyPath != "" {
+		return readPaperKeyFile(keyPath)
+	}
+
+	if len(args) != 1 {
+		return paperKey{}, newUsageError("expected exactly one key name, received [%d]", len(args))
+	}
+
+	entry, err := keystoreOptions.get(args[0])
+	if err != nil {
+		return paperKey{}, err
+	}
+
+	return newPaperKey(entry.Type, entry.Material)
+}
+
+func runPaperExport(args []string) error {
+	flags := newFlagSet("paper export", "[NAME]")
+	keyPath := flags.String("file", "", "back up the secret key or PEM private key in `file` instead of a keystore key")
+	showWords := flags.Bool("words", false, "print the key as words")
+	showQR := flags.Bool("qr", false, "print the key as a QR code in the terminal")
+	pngPath := flags.String("png", "", "write the key as a QR code to the PNG `file`")
+	invert := flags.Bool("invert", false, "draw the terminal QR code for dark text on a light background")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *keyPath != "" && flags.NArg() > 0 {
+		return newUsageError("use either a key name or -file")
+	}
+
+	key, err := exportedPaperKey(flags.Args(), *keyPath, keystoreOptions)
+	if err != nil {
+		return err
+	}
+
+	if !*showWords && !*showQR && *pngPath == "" {
+		*showWords, *showQR = true, true
+	}
+
+	if *showWords && len(key.words) == 0 {
+		return fmt.Errorf("a [%s] key is too large for words, use -qr or -png", key.keyType)
+	}
+
+	var code qr.Code
+	if *showQR || *pngPath != "" {
+		if code, err = encodeQR(key.qrContent()); err != nil {
+			return err
+		}
+	}
+
+	if *pngPath != "" {
+		err = withOutput(*pngPath, 0600, func(output io.Writer) error {
+			return qr.WritePNG(output, code, pngScale)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *showWords {
+		fmt.Printf("encrypto %s key, %d words\n\n", key.keyType, len(key.words))
+		writeWords(os.Stdout, key.words)
+		fmt.Println()
+	}
+
+	if *showQR {
+		return qr.WriteTerminal(os.Stdout, code, *invert)
+	}
+
+	return nil
+}
+
+// readWords skips the numbers that export prints, so a list can be typed
+// back exactly as it appears on paper.
+func readWords(src io.Reader) ([]string, error) {
+	contents, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	words := []string{}
+	for _, field := range strings.Fields(string(contents)) {
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+
+	return words, nil
+}
+
+func runPaperImport(args []string) error {
+	flags := newFlagSet("paper import", "[NAME]")
+	keyType := flags.String("type", keystore.TypeSecret, "the `type` of key the words hold: secret or ed25519")
+	outPath := flags.String("o", "", "write the key to `file` instead of adding it to the keystore")
+	keystoreOptions := addKeystoreFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *keyType != keystore.TypeSecret && *keyType != keystore.TypeEd25519 {
+		return newUsageError("unknown key type [%s], expected secret or ed25519", *keyType)
+	}
+
+	name := ""
+	if *outPath == "" {
+		var err error
+		if name, err = singleFile(flags); err != nil {
+			return err
+		}
+	} else if flags.NArg() > 0 {
+		return newUsageError("use either a key name or -o")
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintln(os.Stderr, "enter the words, then end the input with Ctrl-D")
+	}
+
+	words, err := readWords(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if len(words) == 0 {
+		return errors.New("no words were given")
+	}
+
+	entropy, err := mnemonic.Decode(words)
+	if err != nil {
+		return err
+	}
+
+	entry := keystore.Entry{Name: name, Type: *keyType, Created: time.Now().UTC()}
+	if *keyType == keystore.TypeSecret {
+		if len(entropy) != secretkey.KeySize {
+			return fmt.Errorf("a secret key is written as 24 words, received [%d]", len(words))
+		}
+
+		entry.Material = base64.StdEncoding.EncodeToString(entropy)
+	} else if entry.Material, err = local.Ed25519FromSeed(entropy); err != nil {
+		return err
+	}
+
+	if *outPath != "" {
+		material := entry.Material
+		if entry.Type == keystore.TypeSecret {
+			material += "\n"
+		}
+
+		return withOutput(*outPath, 0600, func(output io.Writer) error {
+			_, err := io.WriteString(output, material)
+			return err
+		})
+	}
+
+	store, err := keystoreOptions.load(true)
+	if err != nil {
+		return err
+	}
+
+	if err = store.Add(entry); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "added [%s] with fingerprint [%s]\n", name, entryFingerprint(entry))
+
+	return keystoreOptions.save(store)
+}
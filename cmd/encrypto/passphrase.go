@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+func readSecretFile(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	contents = bytes.TrimRight(contents, "\r\n")
+	if len(contents) == 0 {
+		return "", fmt.Errorf("[%s] is empty", path)
+	}
+
+	return string(contents), nil
+}
+
+func promptSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", errors.New("no terminal available to prompt on, use a file flag instead")
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	secret, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+// readPassphrase reads a passphrase from path when one is given, otherwise it
+// prompts on the controlling terminal without echo so that key material never
+// has to pass through arguments or the environment.
+func readPassphrase(path string, prompt string, confirm bool) (string, error) {
+	if path != "" {
+		return readSecretFile(path)
+	}
+
+	passphrase, err := promptSecret(prompt)
+	if err != nil {
+		return "", err
+	}
+
+	if passphrase == "" {
+		return "", errors.New("passphrase must not be empty")
+	}
+
+	if confirm {
+		repeated, err := promptSecret("Confirm " + strings.ToLower(prompt))
+		if err != nil {
+			return "", err
+		}
+
+		if repeated != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"util.tim/encrypto/adapters/pgp"
+	"util.tim/encrypto/core/fileformat"
+)
+
+func defaultPGPKeyringPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "encrypto", "pgp-keyring.asc")
+}
+
+type pgpOptions struct {
+	keyrings stringList
+}
+
+func addPGPKeyringFlags(flags *flag.FlagSet) *pgpOptions {
+	options := &pgpOptions{}
+	flags.Var(&options.keyrings, "pgp-keyring", "read OpenPGP keys from the armored or binary keyring `file`, as gpg --export or --export-secret-keys write it, may be repeated")
+
+	return options
+}
+
+// keyring falls back to the keyring in the configuration directory, so
+// keys exported from gpg once can be used without naming them every time.
+// Without any keyring it is empty, which is enough for messages encrypted
+// to a passphrase, unless keys are required.
+func (options *pgpOptions) keyring(required bool) (pgp.Keyring, error) {
+	paths := options.keyrings
+	if len(paths) == 0 {
+		defaultPath := defaultPGPKeyringPath()
+		if _, err := os.Stat(defaultPath); defaultPath == "" || err != nil {
+			if !required {
+				return pgp.ReadKeyring()
+			}
+
+			return nil, newUsageError("no OpenPGP keyring, use -pgp-keyring or export keys to [%s]", defaultPath)
+		}
+
+		paths = stringList{defaultPath}
+	}
+
+	sources := [][]byte{}
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, contents)
+	}
+
+	return pgp.ReadKeyring(sources...)
+}
+
+// pgpPrompt answers every passphrase request from passphraseFile when one
+// is given, and asks on the terminal otherwise.
+func pgpPrompt(passphraseFile string) pgp.Prompt {
+	return func(description string) ([]byte, error) {
+		secret, err := readPassphrase(passphraseFile, fmt.Sprintf("Passphrase for %s: ", description), false)
+		return []byte(secret), err
+	}
+}
+
+// pgpEncryptFlags are the encrypt flags that apply to OpenPGP messages, the
+// others only make sense for encrypto files.
+var pgpEncryptFlags = map[string]bool{
+	"o":               true,
+	"armor":           true,
+	"pgp":             true,
+	"pgp-recipient":   true,
+	"pgp-sign":        true,
+	"pgp-keyring":     true,
+	"passphrase":      true,
+	"passphrase-file": true,
+}
+
+func checkPGPEncryptFlags(flags *flag.FlagSet) error {
+	var err error
+	flags.Visit(func(set *flag.Flag) {
+		if err == nil && !pgpEncryptFlags[set.Name] {
+			err = newUsageError("-%s can not be used for OpenPGP messages", set.Name)
+		}
+	})
+
+	return err
+}
+
+type pgpEncryption struct {
+	options        *pgpOptions
+	recipients     stringList
+	signer         string
+	passphraseFile string
+	armor          bool
+}
+
+func (encryption *pgpEncryption) run(inPath string, outPath string) error {
+	options := pgp.EncryptOptions{
+		Recipients: encryption.recipients,
+		Signer:     encryption.signer,
+		Armor:      encryption.armor,
+	}
+
+	keyring, err := encryption.options.keyring(len(encryption.recipients) > 0)
+	if err != nil {
+		return err
+	}
+
+	if len(encryption.recipients) == 0 {
+		if encryption.signer != "" {
+			return newUsageError("-pgp-sign requires -pgp-recipient")
+		}
+
+		secret, err := readPassphrase(encryption.passphraseFile, "Passphrase: ", true)
+		if err != nil {
+			return err
+		}
+
+		options.Passphrase = []byte(secret)
+	}
+
+	if inPath != stdio {
+		info, err := os.Stat(inPath)
+		if err != nil {
+			return err
+		}
+
+		options.FileName = filepath.Base(inPath)
+		options.ModTime = info.ModTime()
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return withOutput(outPath, 0644, func(output io.Writer) error {
+		writer, err := keyring.Encrypt(output, options, pgpPrompt(encryption.passphraseFile))
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(writer, input); err != nil {
+			return err
+		}
+
+		return writer.Close()
+	})
+}
+
+// decryptPGP writes the plaintext of an OpenPGP message. The literal data
+// packet only records a name and a time, so -restore leaves the mode at
+// 0600.
+func decryptPGP(input io.Reader, outPath string, restore bool, options *pgpOptions, passphraseFile string) error {
+	keyring, err := options.keyring(false)
+	if err != nil {
+		return err
+	}
+
+	message, err := keyring.Decrypt(input, pgpPrompt(passphraseFile))
+	if err != nil {
+		return err
+	}
+
+	if !message.Encrypted {
+		fmt.Fprintln(os.Stderr, "warning: the OpenPGP message is not encrypted")
+	}
+
+	metadata := &fileformat.Metadata{Name: message.FileName, Mode: 0600, ModTime: message.ModTime}
+	if metadata.ModTime.Unix() == 0 {
+		metadata.ModTime = time.Now()
+	}
+
+	if restore && outPath == stdio {
+		if outPath, err = restorePath(metadata); err != nil {
+			return err
+		}
+	}
+
+	// The signature is only known once the plaintext has been read, so
+	// checking it inside withOutput removes the output when it is bad.
+	var signer string
+	err = withOutput(outPath, 0600, func(output io.Writer) error {
+		_, err := io.Copy(output, message)
+		if err != nil {
+			return err
+		}
+
+		signer, err = message.Signer()
+		if errors.Is(err, pgp.ErrUnknownSigner) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if signer != "" {
+		fmt.Fprintf(os.Stderr, "good OpenPGP signature from %s\n", signer)
+	}
+
+	if restore && outPath != stdio {
+		return restoreAttributes(outPath, metadata)
+	}
+
+	return nil
+}
+
+func signPGP(inPath string, outPath string, signer string, options *pgpOptions, passphraseFile string) error {
+	keyring, err := options.keyring(true)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return withOutput(outPath, 0644, func(output io.Writer) error {
+		return keyring.Sign(output, input, signer, pgpPrompt(passphraseFile))
+	})
+}
+
+func verifyPGP(inPath string, signaturePath string, options *pgpOptions) error {
+	keyring, err := options.keyring(true)
+	if err != nil {
+		return err
+	}
+
+	signature, err := os.Open(signaturePath)
+	if err != nil {
+		return err
+	}
+	defer signature.Close()
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	signer, err := keyring.Verify(input, signature)
+	if err != nil {
+		return fmt.Errorf("could not verify the OpenPGP signature: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "good OpenPGP signature from %s\n", signer)
+	return nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"util.tim/encrypto/adapters/asymetric/remote"
+	"util.tim/encrypto/adapters/asymetric/sshkey"
+	"util.tim/encrypto/adapters/symmetric/passphrase"
+	"util.tim/encrypto/adapters/symmetric/secretkey"
+	"util.tim/encrypto/adapters/symmetric/sharing"
+	"util.tim/encrypto/core/armor"
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/fileformat"
+)
+
+func stanzaFingerprint(stanza fileformat.Stanza) string {
+	switch stanza.Type {
+	case asymetric.OAEPStanzaType:
+		var params asymetric.OAEPStanzaParams
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return params.Fingerprint
+	case secretkey.StanzaType:
+		var params secretkey.Params
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return params.Id
+	case sshkey.StanzaRSA, sshkey.StanzaEd25519, sshkey.StanzaAgent:
+		var params sshkey.Params
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return params.Fingerprint
+	case sharing.StanzaType:
+		var params sharing.Params
+		if err := json.Unmarshal(stanza.Params, &params); err != nil {
+			return ""
+		}
+
+		return params.Group
+	default:
+		return ""
+	}
+}
+
+// rewriteFile replaces path with the output of rewrite, going through a
+// temporary file in the same directory so the original survives any failure.
+// Armored files stay armored.
+func rewriteFile(path string, rewrite func(dst io.Writer, src io.Reader) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".encrypto-")
+	if err != nil {
+		return err
+	}
+
+	raw, isArmored, err := armor.Detect(src)
+	if err == nil {
+		err = withArmor(tmp, isArmored, func(dst io.Writer) error {
+			return rewrite(dst, raw)
+		})
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+func runRecipients(args []string) error {
+	if len(args) == 0 {
+		return newUsageError("expected one of list, add or remove")
+	}
+
+	switch args[0] {
+	case "list":
+		return runRecipientsList(args[1:])
+	case "add":
+		return runRecipientsAdd(args[1:])
+	case "remove":
+		return runRecipientsRemove(args[1:])
+	default:
+		return newUsageError("unknown recipients command [%s], expected one of list, add or remove", args[0])
+	}
+}
+
+func runRecipientsList(args []string) error {
+	flags := newFlagSet("recipients list", "FILE")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	raw, _, err := armor.Detect(file)
+	if err != nil {
+		return err
+	}
+
+	header, err := fileformat.ReadHeader(raw)
+	if err != nil {
+		return err
+	}
+
+	for index, stanza := range header.Stanzas {
+		fmt.Printf("%d\t%s\t%s\n", index, stanza.Type, stanzaFingerprint(stanza))
+	}
+
+	return nil
+}
+
+func runRecipientsAdd(args []string) error {
+	flags := newFlagSet("recipients add", "FILE")
+	identityOptions := addIdentityFlags(flags)
+	recipientPaths := stringList{}
+	flags.Var(&recipientPaths, "recipient", "add the RSA public key in PEM `file`, may be repeated")
+	sshKeys := stringList{}
+	flags.Var(&sshKeys, "ssh-recipient", "add the ssh-rsa and ssh-ed25519 keys in authorized_keys `file`, or a key given inline, may be repeated")
+	// -ssh-agent already names the agent as an identity to open the file with.
+	addAgent := flags.Bool("add-ssh-agent", false, "add the keys in ssh-agent")
+	recipientKeys := stringList{}
+	flags.Var(&recipientKeys, "recipient-key", "add the keystore key called `name`, may be repeated")
+	addPassphrase := flags.Bool("add-passphrase", false, "add a new passphrase")
+	newPassphraseFile := flags.String("new-passphrase-file", "", "add the passphrase in `file`")
+	shareOptions := addShareFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	if err := shareOptions.validate(path); err != nil {
+		return err
+	}
+
+	recipients, err := loadRecipients(recipientPaths)
+	if err != nil {
+		return err
+	}
+
+	sshRecipients, err := loadSSHRecipients(sshKeys)
+	if err != nil {
+		return err
+	}
+	recipients = append(recipients, sshRecipients...)
+
+	if *addAgent {
+		fromAgent, err := agentRecipients()
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, fromAgent...)
+	}
+
+	named, err := identityOptions.keystore.recipients(recipientKeys)
+	if err != nil {
+		return err
+	}
+	recipients = append(recipients, named...)
+
+	shared, err := shareOptions.recipients()
+	if err != nil {
+		return err
+	}
+	recipients = append(recipients, shared...)
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	if *addPassphrase || *newPassphraseFile != "" {
+		secret, err := readPassphrase(*newPassphraseFile, "New passphrase: ", true)
+		if err != nil {
+			return err
+		}
+
+		recipients = append(recipients, passphrase.NewRecipient(secret, passphrase.DefaultParams()))
+	}
+
+	if len(recipients) == 0 {
+		return newUsageError("nothing to add, use -recipient, -ssh-recipient, -add-ssh-agent, -recipient-key, -add-passphrase or -shares")
+	}
+
+	err = rewriteFile(path, func(dst io.Writer, src io.Reader) error {
+		return fileformat.Rewrite(dst, src, identities, fileformat.AddRecipients(recipients...))
+	})
+	if err != nil {
+		return err
+	}
+
+	return shareOptions.write()
+}
+
+func runRecipientsRemove(args []string) error {
+	flags := newFlagSet("recipients remove", "FILE")
+	identityOptions := addIdentityFlags(flags)
+	recipientPaths := stringList{}
+	flags.Var(&recipientPaths, "recipient", "remove the RSA public key in PEM `file`, may be repeated")
+	fingerprints := stringList{}
+	flags.Var(&fingerprints, "fingerprint", "remove the recipient with `fingerprint`, may be repeated")
+	indexes := intList{}
+	flags.Var(&indexes, "index", "remove the stanza at `index` as shown by list, may be repeated")
+	removePassphrases := flags.Bool("passphrases", false, "remove every passphrase stanza")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	path, err := singleFile(flags)
+	if err != nil {
+		return err
+	}
+
+	for _, recipientPath := range recipientPaths {
+		pemString, err := readKeyFile(recipientPath)
+		if err != nil {
+			return err
+		}
+
+		fingerprint, err := remote.Fingerprint(pemString)
+		if err != nil {
+			return fmt.Errorf("could not load recipient [%s]: %w", recipientPath, err)
+		}
+
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	if len(fingerprints) == 0 && len(indexes) == 0 && !*removePassphrases {
+		return newUsageError("nothing to remove, use -recipient, -fingerprint, -index or -passphrases")
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	matches := func(index int, stanza fileformat.Stanza) bool {
+		if *removePassphrases && stanza.Type == passphrase.StanzaType {
+			return true
+		}
+
+		for _, removed := range indexes {
+			if removed == index {
+				return true
+			}
+		}
+
+		fingerprint := stanzaFingerprint(stanza)
+		for _, removed := range fingerprints {
+			if fingerprint != "" && removed == fingerprint {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return rewriteFile(path, func(dst io.Writer, src io.Reader) error {
+		return fileformat.Rewrite(dst, src, identities, fileformat.RemoveStanzas(matches))
+	})
+}
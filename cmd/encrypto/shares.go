@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"util.tim/encrypto/adapters/symmetric/sharing"
+	"util.tim/encrypto/core/fileformat"
+)
+
+type shareOptions struct {
+	total     *int
+	threshold *int
+	prefix    *string
+	recipient sharing.Recipient
+}
+
+func addShareFlags(flags *flag.FlagSet) *shareOptions {
+	return &shareOptions{
+		total:     flags.Int("shares", 0, "split the file key into `n` shares"),
+		threshold: flags.Int("threshold", 0, "require `m` shares to decrypt, defaults to all of them"),
+		prefix:    flags.String("share-prefix", "", "write shares to `prefix`.share-N, defaults to the output file, - prints them"),
+	}
+}
+
+func (options *shareOptions) enabled() bool {
+	return *options.total > 0 || *options.threshold > 0
+}
+
+// validate runs before anything is written so a missing share destination
+// is reported before the ciphertext exists.
+func (options *shareOptions) validate(outPath string) error {
+	if !options.enabled() {
+		return nil
+	}
+
+	if *options.total == 0 {
+		return newUsageError("-threshold needs -shares")
+	}
+
+	if *options.prefix == "" {
+		*options.prefix = outPath
+	}
+
+	if *options.prefix == stdio && outPath == stdio {
+		return newUsageError("shares and ciphertext can not both go to stdout, use -o or -share-prefix")
+	}
+
+	if *options.prefix == stdio {
+		return nil
+	}
+
+	for _, path := range options.paths() {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("refusing to overwrite the existing share [%s]", path)
+		}
+	}
+
+	return nil
+}
+
+func (options *shareOptions) paths() []string {
+	paths := make([]string, *options.total)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("%s.share-%d", *options.prefix, i+1)
+	}
+
+	return paths
+}
+
+func (options *shareOptions) recipients() ([]fileformat.Recipient, error) {
+	if !options.enabled() {
+		return nil, nil
+	}
+
+	threshold := *options.threshold
+	if threshold == 0 {
+		threshold = *options.total
+	}
+
+	recipient, err := sharing.NewRecipient(*options.total, threshold)
+	if err != nil {
+		return nil, err
+	}
+	options.recipient = recipient
+
+	return []fileformat.Recipient{recipient}, nil
+}
+
+// write hands out the shares once the file that needs them is in place.
+func (options *shareOptions) write() error {
+	if options.recipient == nil {
+		return nil
+	}
+
+	shares := options.recipient.Shares()
+	if *options.prefix == stdio {
+		for _, share := range shares {
+			if _, err := os.Stdout.Write(share.Encode()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for i, path := range options.paths() {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+
+		_, err = file.Write(shares[i].Encode())
+		closeErr := file.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "wrote share %d of %d to %s\n", i+1, len(shares), path)
+	}
+
+	return nil
+}
+
+func loadShares(paths []string) ([]fileformat.Identity, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	shares := []sharing.Share{}
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := sharing.ParseShares(contents)
+		if err != nil {
+			return nil, fmt.Errorf("could not load share [%s]: %w", path, err)
+		}
+
+		shares = append(shares, parsed...)
+	}
+
+	identity, err := sharing.NewIdentity(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	return []fileformat.Identity{identity}, nil
+}
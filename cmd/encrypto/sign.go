@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"util.tim/encrypto/adapters/asymetric/local"
+	"util.tim/encrypto/adapters/asymetric/remote"
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/signature"
+)
+
+func loadSigner(path string) (asymetric.Signer, error) {
+	pemString, err := readKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := local.NewSignerFromPEM(pemString)
+	if err != nil {
+		return nil, fmt.Errorf("could not load signing key [%s]: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+func loadVerifiers(paths []string) ([]asymetric.Verifier, error) {
+	verifiers := []asymetric.Verifier{}
+
+	for _, path := range paths {
+		pemString, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		verifier, err := remote.NewVerifierFromPEM(pemString)
+		if err != nil {
+			return nil, fmt.Errorf("could not load verifying key [%s]: %w", path, err)
+		}
+
+		verifiers = append(verifiers, verifier)
+	}
+
+	return verifiers, nil
+}
+
+func signaturePathFor(inPath string, signaturePath string) (string, error) {
+	if signaturePath != "" {
+		return signaturePath, nil
+	}
+
+	if inPath == stdio {
+		return "", newUsageError("-signature is required when the signed data is on stdin or stdout")
+	}
+
+	return inPath + ".sig", nil
+}
+
+// pgpSignaturePathFor follows gpg, which names armored detached signatures
+// INPUT.asc.
+func pgpSignaturePathFor(inPath string, signaturePath string) (string, error) {
+	if signaturePath != "" || inPath == stdio {
+		return signaturePathFor(inPath, signaturePath)
+	}
+
+	return inPath + ".asc", nil
+}
+
+// signingWriter hashes everything written through it, so a file can be
+// signed in the same pass that writes it.
+type signingWriter struct {
+	signer asymetric.Signer
+	hasher hash.Hash
+}
+
+func newSigningWriter(signer asymetric.Signer) *signingWriter {
+	return &signingWriter{
+		signer: signer,
+		hasher: signature.NewHash(),
+	}
+}
+
+func (writer *signingWriter) wrap(output io.Writer) io.Writer {
+	if writer.signer == nil {
+		return output
+	}
+
+	return io.MultiWriter(output, writer.hasher)
+}
+
+func (writer *signingWriter) writeSignature(path string) error {
+	if writer.signer == nil {
+		return nil
+	}
+
+	signed, err := signature.SignDigest(writer.hasher.Sum(nil), writer.signer)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, signed, 0644)
+}
+
+func runSign(args []string) error {
+	flags := newFlagSet("sign", "[INPUT]")
+	keyPath := flags.String("key", "", "sign with the RSA or Ed25519 private key in PEM `file`")
+	signaturePath := flags.String("o", "", "write the signature to `file`, defaults to INPUT.sig, or INPUT.asc with -pgp-key")
+	pgpKey := flags.String("pgp-key", "", "write an armored OpenPGP signature with the keyring secret key matching `query` instead")
+	pgpOptions := addPGPKeyringFlags(flags)
+	passphraseFile := flags.String("passphrase-file", "", "read the passphrase of the -pgp-key from `file` instead of prompting")
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if (*keyPath == "") == (*pgpKey == "") {
+		return newUsageError("exactly one of -key or -pgp-key is required")
+	}
+
+	if *pgpKey != "" {
+		outPath, err := pgpSignaturePathFor(inPath, *signaturePath)
+		if err != nil {
+			return err
+		}
+
+		return signPGP(inPath, outPath, *pgpKey, pgpOptions, *passphraseFile)
+	}
+
+	outPath, err := signaturePathFor(inPath, *signaturePath)
+	if err != nil {
+		return err
+	}
+
+	signer, err := loadSigner(*keyPath)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	signed, err := signature.Sign(input, signer)
+	if err != nil {
+		return err
+	}
+
+	return withOutput(outPath, 0644, func(output io.Writer) error {
+		_, err := output.Write(signed)
+		return err
+	})
+}
+
+func verifyFile(inPath string, signaturePath string, verifiers []asymetric.Verifier) (asymetric.Verifier, error) {
+	signed, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return nil, err
+	}
+
+	input, err := openInput(inPath)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	return signature.Verify(input, signed, verifiers...)
+}
+
+func runVerify(args []string) error {
+	flags := newFlagSet("verify", "[INPUT]")
+	keyPaths := stringList{}
+	flags.Var(&keyPaths, "key", "accept signatures from the RSA or Ed25519 public key in PEM `file`, may be repeated")
+	signaturePath := flags.String("signature", "", "read the signature from `file`, defaults to INPUT.sig, or INPUT.asc with -pgp")
+	usePGP := flags.Bool("pgp", false, "check an OpenPGP signature against the keys in the keyring")
+	pgpOptions := addPGPKeyringFlags(flags)
+	identityOptions := addIdentityFlags(flags)
+
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	inPath, err := singleInput(flags)
+	if err != nil {
+		return err
+	}
+
+	if *usePGP {
+		if len(keyPaths) > 0 || identityOptions.given() {
+			return newUsageError("-pgp can not be combined with -key or identities")
+		}
+
+		sigPath, err := pgpSignaturePathFor(inPath, *signaturePath)
+		if err != nil {
+			return err
+		}
+
+		return verifyPGP(inPath, sigPath, pgpOptions)
+	}
+
+	// With only -key this checks a signature, otherwise it authenticates
+	// the encrypted file itself, and with both it does both.
+	if len(keyPaths) > 0 {
+		sigPath, err := signaturePathFor(inPath, *signaturePath)
+		if err != nil {
+			return err
+		}
+
+		verifiers, err := loadVerifiers(keyPaths)
+		if err != nil {
+			return err
+		}
+
+		verifier, err := verifyFile(inPath, sigPath, verifiers)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "good %s signature from %s\n", verifier.Algorithm(), asymetric.Fingerprint(verifier.PublicKeyBytes()))
+
+		if !identityOptions.given() {
+			return nil
+		}
+	}
+
+	identities, err := identityOptions.identities()
+	if err != nil {
+		return err
+	}
+
+	checked, err := checkFile(inPath, identities)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "every chunk authenticated, %d bytes of plaintext\n", checked)
+	return nil
+}
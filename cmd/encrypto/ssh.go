@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/agent"
+	"util.tim/encrypto/adapters/asymetric/sshkey"
+	"util.tim/encrypto/core/fileformat"
+)
+
+// loadSSHRecipients accepts authorized_keys files as well as a key pasted
+// directly into the flag.
+func loadSSHRecipients(values []string) ([]fileformat.Recipient, error) {
+	recipients := []fileformat.Recipient{}
+
+	for _, value := range values {
+		if strings.HasPrefix(value, "ssh-") {
+			recipient, err := sshkey.NewRecipient([]byte(value))
+			if err != nil {
+				return nil, err
+			}
+
+			recipients = append(recipients, recipient)
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(value)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed, err := sshkey.ParseRecipients(contents)
+		if err != nil {
+			return nil, fmt.Errorf("could not load SSH recipients [%s]: %w", value, err)
+		}
+
+		recipients = append(recipients, parsed...)
+	}
+
+	return recipients, nil
+}
+
+func loadSSHIdentities(paths []string) ([]fileformat.Identity, error) {
+	identities := []fileformat.Identity{}
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		identity, err := sshkey.NewIdentity(contents)
+		if errors.Is(err, sshkey.ErrPassphraseRequired) {
+			var secret string
+			if secret, err = promptSecret(fmt.Sprintf("Passphrase for %s: ", path)); err != nil {
+				return nil, err
+			}
+
+			identity, err = sshkey.NewIdentityWithPassphrase(contents, []byte(secret))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not load SSH identity [%s]: %w", path, err)
+		}
+
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+// connectAgent opens the agent named by SSH_AUTH_SOCK. The connection stays
+// open for the rest of the command.
+func connectAgent() (agent.ExtendedAgent, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, errors.New("SSH_AUTH_SOCK is not set, start ssh-agent first")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to ssh-agent: %w", err)
+	}
+
+	return agent.NewClient(conn), nil
+}
+
+func agentRecipients() ([]fileformat.Recipient, error) {
+	client, err := connectAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	return sshkey.NewAgentRecipients(client)
+}
+
+func agentIdentity() (fileformat.Identity, error) {
+	client, err := connectAgent()
+	if err != nil {
+		return nil, err
+	}
+
+	return sshkey.NewAgentIdentity(client)
+}
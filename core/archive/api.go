@@ -0,0 +1,33 @@
+package archive
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	TypeDirectory = "dir"
+	TypeFile      = "file"
+)
+
+type Entry struct {
+	Path    string      `json:"path"`
+	Type    string      `json:"type"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	Size    int64       `json:"size,omitempty"`
+	Offset  int64       `json:"offset,omitempty"`
+}
+
+type Manifest struct {
+	Entries []Entry `json:"entries"`
+}
+
+type Archive interface {
+	Entries() []Entry
+	Find(path string) (Entry, bool)
+	Open(entry Entry) io.Reader
+	Extract(entry Entry, dir string) error
+	ExtractAll(dir string) error
+}
@@ -0,0 +1,157 @@
+package archive_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/archive"
+)
+
+func writeTestFile(t *testing.T, path string, contents string, mode os.FileMode, modTime time.Time) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Log("MkdirAll failed", err)
+		t.FailNow()
+	}
+
+	if err := ioutil.WriteFile(path, []byte(contents), mode); err != nil {
+		t.Log("WriteFile failed", err)
+		t.FailNow()
+	}
+
+	os.Chmod(path, mode)
+	os.Chtimes(path, modTime, modTime)
+}
+
+func newTestTree(t *testing.T) string {
+	root, err := ioutil.TempDir("", "archive-source")
+	if err != nil {
+		t.Log("TempDir failed", err)
+		t.FailNow()
+	}
+
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writeTestFile(t, filepath.Join(root, "top.txt"), "top level", 0644, modTime)
+	writeTestFile(t, filepath.Join(root, "nested", "deeper", "script.sh"), "#!/bin/sh\necho hi\n", 0755, modTime)
+	writeTestFile(t, filepath.Join(root, "nested", "empty"), "", 0600, modTime)
+
+	return root
+}
+
+func createForTest(t *testing.T, root string) archive.Archive {
+	buffer := bytes.NewBuffer(nil)
+
+	err := archive.Create(buffer, root, func(path string, reason string) {
+		t.Log(fmt.Sprintf("Unexpected skip of [%s]: %s", path, reason))
+		t.Fail()
+	})
+	if err != nil {
+		t.Log("Create failed", err)
+		t.FailNow()
+	}
+
+	opened, err := archive.Open(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Log("Open failed", err)
+		t.FailNow()
+	}
+
+	return opened
+}
+
+func Test_ListsRelativePaths(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+
+	opened := createForTest(t, root)
+
+	paths := []string{}
+	for _, entry := range opened.Entries() {
+		paths = append(paths, entry.Path)
+	}
+
+	expected := []string{"nested", "nested/deeper", "nested/deeper/script.sh", "nested/empty", "top.txt"}
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Log(fmt.Sprintf("Expected %v received %v", expected, paths))
+		t.Fail()
+	}
+}
+
+func Test_CanReadASingleFile(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+
+	opened := createForTest(t, root)
+
+	entry, found := opened.Find("nested/deeper/script.sh")
+	if !found {
+		t.Log("Expected to find the script")
+		t.FailNow()
+	}
+
+	contents, _ := ioutil.ReadAll(opened.Open(entry))
+	if string(contents) != "#!/bin/sh\necho hi\n" {
+		t.Log(fmt.Sprintf("Unexpected contents [%s]", string(contents)))
+		t.Fail()
+	}
+}
+
+func Test_ExtractRestoresModesAndTimes(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+
+	target, _ := ioutil.TempDir("", "archive-target")
+	defer os.RemoveAll(target)
+
+	if err := createForTest(t, root).ExtractAll(target); err != nil {
+		t.Log("ExtractAll failed", err)
+		t.FailNow()
+	}
+
+	info, err := os.Stat(filepath.Join(target, "nested", "deeper", "script.sh"))
+	if err != nil {
+		t.Log("Expected the script to be extracted", err)
+		t.FailNow()
+	}
+
+	if info.Mode().Perm() != 0755 {
+		t.Log(fmt.Sprintf("Expected mode 0755 received %o", info.Mode().Perm()))
+		t.Fail()
+	}
+
+	if !info.ModTime().Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Log(fmt.Sprintf("Unexpected mtime [%s]", info.ModTime()))
+		t.Fail()
+	}
+}
+
+func Test_RefusesPathsOutsideTheTarget(t *testing.T) {
+	manifest, _ := json.Marshal(archive.Manifest{Entries: []archive.Entry{
+		{Path: "../escaped", Type: archive.TypeFile, Mode: 0644, Size: 1},
+	}})
+
+	buffer := bytes.NewBuffer([]byte("ENCRYPTO-ARCHIVE"))
+	binary.Write(buffer, binary.BigEndian, uint64(len(manifest)))
+	buffer.Write(manifest)
+	buffer.WriteByte('x')
+
+	opened, err := archive.Open(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Log("Open failed", err)
+		t.FailNow()
+	}
+
+	target, _ := ioutil.TempDir("", "archive-target")
+	defer os.RemoveAll(target)
+
+	if err = opened.ExtractAll(filepath.Join(target, "inner")); err == nil {
+		t.Log("Expected extraction of a parent path to fail")
+		t.Fail()
+	}
+}
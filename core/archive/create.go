@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var magic = []byte("ENCRYPTO-ARCHIVE")
+
+func buildManifest(root string, onSkip func(path string, reason string)) (Manifest, error) {
+	manifest := Manifest{Entries: []Entry{}}
+	offset := int64(0)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if relative == "." {
+			return nil
+		}
+
+		entry := Entry{
+			Path:    filepath.ToSlash(relative),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		}
+
+		switch {
+		case info.IsDir():
+			entry.Type = TypeDirectory
+		case info.Mode().IsRegular():
+			entry.Type = TypeFile
+			entry.Size = info.Size()
+			entry.Offset = offset
+			offset += info.Size()
+		default:
+			onSkip(path, fmt.Sprintf("unsupported file type [%s]", info.Mode()&os.ModeType))
+			return nil
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+		return nil
+	})
+
+	return manifest, err
+}
+
+func copyExactly(dst io.Writer, path string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = io.CopyN(dst, file, size); err == io.EOF {
+		return fmt.Errorf("[%s] shrank while it was being archived", path)
+	} else if err != nil {
+		return err
+	}
+
+	if n, _ := file.Read(make([]byte, 1)); n != 0 {
+		return fmt.Errorf("[%s] grew while it was being archived", path)
+	}
+
+	return nil
+}
+
+// Create writes the directory tree under root to dst. The manifest is written
+// first with the offset of every file, so a reader can list the archive or pull
+// out a single file without reading the rest.
+func Create(dst io.Writer, root string, onSkip func(path string, reason string)) error {
+	manifest, err := buildManifest(root, onSkip)
+	if err != nil {
+		return err
+	}
+
+	manifestJson, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	if _, err = dst.Write(magic); err != nil {
+		return err
+	}
+
+	if err = binary.Write(dst, binary.BigEndian, uint64(len(manifestJson))); err != nil {
+		return err
+	}
+
+	if _, err = dst.Write(manifestJson); err != nil {
+		return err
+	}
+
+	for _, entry := range manifest.Entries {
+		if entry.Type != TypeFile {
+			continue
+		}
+
+		if err = copyExactly(dst, filepath.Join(root, filepath.FromSlash(entry.Path)), entry.Size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
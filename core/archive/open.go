@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"util.tim/encrypto/core/internal/extract"
+)
+
+const maxManifestBytes = 256 * 1024 * 1024
+
+type archive struct {
+	src       io.ReaderAt
+	manifest  Manifest
+	dataStart int64
+}
+
+func (archive *archive) Entries() []Entry {
+	return archive.manifest.Entries
+}
+
+func (archive *archive) Find(entryPath string) (Entry, bool) {
+	for _, entry := range archive.manifest.Entries {
+		if entry.Path == entryPath {
+			return entry, true
+		}
+	}
+
+	return Entry{}, false
+}
+
+func (archive *archive) Open(entry Entry) io.Reader {
+	return io.NewSectionReader(archive.src, archive.dataStart+entry.Offset, entry.Size)
+}
+
+// toExtract checks the entry type, since extract only knows directories and
+// files.
+func (archive *archive) toExtract(entry Entry) (extract.Entry, error) {
+	if entry.Type != TypeFile && entry.Type != TypeDirectory {
+		return extract.Entry{}, fmt.Errorf("unknown entry type [%s] for [%s]", entry.Type, entry.Path)
+	}
+
+	return extract.Entry{
+		Path:      entry.Path,
+		Directory: entry.Type == TypeDirectory,
+		Mode:      entry.Mode,
+		ModTime:   entry.ModTime,
+		Write: func(dst io.Writer) error {
+			_, err := io.Copy(dst, archive.Open(entry))
+			return err
+		},
+	}, nil
+}
+
+func (archive *archive) Extract(entry Entry, dir string) error {
+	converted, err := archive.toExtract(entry)
+	if err != nil {
+		return err
+	}
+
+	return extract.Extract(converted, dir)
+}
+
+func (archive *archive) ExtractAll(dir string) error {
+	entries := []extract.Entry{}
+	for _, entry := range archive.manifest.Entries {
+		converted, err := archive.toExtract(entry)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, converted)
+	}
+
+	return extract.ExtractAll(entries, dir)
+}
+
+func Open(src io.ReaderAt, size int64) (Archive, error) {
+	prefix := make([]byte, len(magic)+8)
+	if _, err := src.ReadAt(prefix, 0); err != nil {
+		return nil, fmt.Errorf("could not read archive: %w", err)
+	}
+
+	if !bytes.Equal(prefix[:len(magic)], magic) {
+		return nil, errors.New("input is not an encrypto archive")
+	}
+
+	length := binary.BigEndian.Uint64(prefix[len(magic):])
+	if length > maxManifestBytes || int64(length) > size-int64(len(prefix)) {
+		return nil, fmt.Errorf("manifest length [%d] is invalid", length)
+	}
+
+	manifestJson := make([]byte, length)
+	if _, err := src.ReadAt(manifestJson, int64(len(prefix))); err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestJson, &manifest); err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %w", err)
+	}
+
+	dataStart := int64(len(prefix)) + int64(length)
+	for _, entry := range manifest.Entries {
+		if entry.Size < 0 || entry.Offset < 0 || dataStart+entry.Offset+entry.Size > size {
+			return nil, fmt.Errorf("entry [%s] lies outside of the archive", entry.Path)
+		}
+	}
+
+	return &archive{
+		src:       src,
+		manifest:  manifest,
+		dataStart: dataStart,
+	}, nil
+}
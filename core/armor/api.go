@@ -0,0 +1,245 @@
+package armor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	Header     = "-----BEGIN ENCRYPTO FILE-----"
+	Footer     = "-----END ENCRYPTO FILE-----"
+	lineLength = 64
+	maxLine    = 4096
+)
+
+const (
+	crc24Init = 0xb704ce
+	crc24Poly = 0x1864cfb
+)
+
+func updateCRC24(crc uint32, data []byte) uint32 {
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= crc24Poly
+			}
+		}
+	}
+
+	return crc & 0xffffff
+}
+
+func encodeCRC24(crc uint32) string {
+	return "=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)})
+}
+
+type encoder struct {
+	dst     io.Writer
+	pending []byte
+	crc     uint32
+	started bool
+	closed  bool
+}
+
+func (encoder *encoder) writeLine(line string) error {
+	_, err := io.WriteString(encoder.dst, line+"\n")
+	return err
+}
+
+func (encoder *encoder) Write(p []byte) (int, error) {
+	if encoder.closed {
+		return 0, errors.New("write to a closed armor encoder")
+	}
+
+	if !encoder.started {
+		encoder.started = true
+		if err := encoder.writeLine(Header); err != nil {
+			return 0, err
+		}
+	}
+
+	encoder.crc = updateCRC24(encoder.crc, p)
+	encoder.pending = append(encoder.pending, p...)
+
+	chunk := lineLength / 4 * 3
+	for len(encoder.pending) >= chunk {
+		if err := encoder.writeLine(base64.StdEncoding.EncodeToString(encoder.pending[:chunk])); err != nil {
+			return 0, err
+		}
+
+		encoder.pending = encoder.pending[chunk:]
+	}
+
+	return len(p), nil
+}
+
+func (encoder *encoder) Close() error {
+	if encoder.closed {
+		return nil
+	}
+
+	if _, err := encoder.Write(nil); err != nil {
+		return err
+	}
+	encoder.closed = true
+
+	if len(encoder.pending) > 0 {
+		if err := encoder.writeLine(base64.StdEncoding.EncodeToString(encoder.pending)); err != nil {
+			return err
+		}
+	}
+
+	if err := encoder.writeLine(encodeCRC24(encoder.crc)); err != nil {
+		return err
+	}
+
+	return encoder.writeLine(Footer)
+}
+
+// NewEncoder wraps everything written to it in a BEGIN/END block of base64
+// lines followed by a CRC-24 checksum line, in the style of OpenPGP armor.
+func NewEncoder(dst io.Writer) io.WriteCloser {
+	return &encoder{
+		dst: dst,
+		crc: crc24Init,
+	}
+}
+
+type decoder struct {
+	src      *bufio.Reader
+	pending  []byte
+	carry    string
+	crc      uint32
+	checksum string
+	started  bool
+	done     bool
+}
+
+func (decoder *decoder) readLine() (string, error) {
+	line, err := decoder.src.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) > maxLine {
+		return "", errors.New("armored line is too long")
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func (decoder *decoder) finish() error {
+	if decoder.carry != "" {
+		return errors.New("armored data has a truncated base64 group")
+	}
+
+	if decoder.checksum != "" && decoder.checksum != encodeCRC24(decoder.crc) {
+		return errors.New("armor checksum does not match, the text may have been damaged")
+	}
+
+	decoder.done = true
+	return nil
+}
+
+func (decoder *decoder) decodeLine(line string) error {
+	encoded := decoder.carry + line
+	usable := len(encoded) / 4 * 4
+	decoder.carry = encoded[usable:]
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded[:usable])
+	if err != nil {
+		return fmt.Errorf("armored data is not valid base64: %w", err)
+	}
+
+	decoder.crc = updateCRC24(decoder.crc, decoded)
+	decoder.pending = decoded
+
+	return nil
+}
+
+func (decoder *decoder) fill() error {
+	for len(decoder.pending) == 0 && !decoder.done {
+		line, err := decoder.readLine()
+		if err == io.EOF {
+			return errors.New("armored data ends without an END line")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !decoder.started:
+			if line == "" {
+				continue
+			}
+			if line != Header {
+				return errors.New("armored data does not start with a BEGIN line")
+			}
+			decoder.started = true
+		case line == Footer:
+			return decoder.finish()
+		case strings.HasPrefix(line, "="):
+			decoder.checksum = line
+		case line == "":
+			continue
+		case decoder.checksum != "":
+			return errors.New("armored data continues after the checksum line")
+		default:
+			if err = decoder.decodeLine(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (decoder *decoder) Read(p []byte) (int, error) {
+	if err := decoder.fill(); err != nil {
+		return 0, err
+	}
+
+	if len(decoder.pending) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, decoder.pending)
+	decoder.pending = decoder.pending[n:]
+
+	return n, nil
+}
+
+func NewDecoder(src io.Reader) io.Reader {
+	return &decoder{
+		src: bufio.NewReader(src),
+		crc: crc24Init,
+	}
+}
+
+// Detect returns a reader over the raw bytes of src, decoding it first when it
+// starts with an armor header, and reports whether it did. Leading whitespace
+// is allowed so blocks pasted into other documents are still recognised.
+func Detect(src io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReaderSize(src, maxLine)
+
+	prefix, err := buffered.Peek(maxLine)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, false, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimLeft(prefix, " \t\r\n"), []byte(Header)) {
+		return NewDecoder(buffered), true, nil
+	}
+
+	return buffered, false, nil
+}
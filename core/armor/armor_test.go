@@ -0,0 +1,112 @@
+package armor_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/armor"
+)
+
+func armorForTest(t *testing.T, data []byte) string {
+	buffer := bytes.NewBuffer(nil)
+	encoder := armor.NewEncoder(buffer)
+
+	// Uneven writes exercise the line buffering.
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+
+		encoder.Write(data[:n])
+		data = data[n:]
+	}
+
+	if err := encoder.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	return buffer.String()
+}
+
+func Test_CanRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 47, 48, 49, 1000} {
+		data := bytes.Repeat([]byte{0xa5}, size)
+		armored := armorForTest(t, data)
+
+		if !strings.HasPrefix(armored, armor.Header+"\n") || !strings.HasSuffix(armored, armor.Footer+"\n") {
+			t.Log(fmt.Sprintf("Expected BEGIN and END lines, received\n%s", armored))
+			t.Fail()
+		}
+
+		decoded, err := ioutil.ReadAll(armor.NewDecoder(strings.NewReader(armored)))
+		if err != nil {
+			t.Log(fmt.Sprintf("Decode of [%d] bytes failed", size), err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(decoded, data) {
+			t.Log(fmt.Sprintf("Expected the [%d] decoded bytes to match", size))
+			t.Fail()
+		}
+	}
+}
+
+func Test_DetectsIndentedArmor(t *testing.T) {
+	armored := armorForTest(t, []byte("pasted into yaml"))
+	indented := "\n    " + strings.Replace(armored, "\n", "\r\n    ", -1)
+
+	reader, isArmored, err := armor.Detect(strings.NewReader(indented))
+	if err != nil || !isArmored {
+		t.Log("Expected indented armor to be detected", err)
+		t.FailNow()
+	}
+
+	decoded, err := ioutil.ReadAll(reader)
+	if err != nil || string(decoded) != "pasted into yaml" {
+		t.Log(fmt.Sprintf("Unexpected decode [%s]", string(decoded)), err)
+		t.Fail()
+	}
+}
+
+func Test_PassesThroughRawInput(t *testing.T) {
+	raw := []byte("  ENCRYPTO\x00\x01 binary")
+
+	reader, isArmored, err := armor.Detect(bytes.NewReader(raw))
+	if err != nil || isArmored {
+		t.Log("Expected raw input not to be detected as armor", err)
+		t.FailNow()
+	}
+
+	passed, _ := ioutil.ReadAll(reader)
+	if !bytes.Equal(passed, raw) {
+		t.Log("Expected raw input to be passed through untouched")
+		t.Fail()
+	}
+}
+
+func Test_DamagedArmorFails(t *testing.T) {
+	armored := armorForTest(t, bytes.Repeat([]byte("checksummed "), 20))
+	lines := strings.Split(armored, "\n")
+	lines[2] = strings.Replace(lines[2], lines[2][:4], "AAAA", 1)
+
+	_, err := ioutil.ReadAll(armor.NewDecoder(strings.NewReader(strings.Join(lines, "\n"))))
+	if err == nil {
+		t.Log("Expected a damaged line to fail the checksum")
+		t.Fail()
+	}
+}
+
+func Test_TruncatedArmorFails(t *testing.T) {
+	armored := armorForTest(t, bytes.Repeat([]byte("truncated "), 20))
+
+	_, err := ioutil.ReadAll(armor.NewDecoder(strings.NewReader(armored[:len(armored)/2])))
+	if err == nil {
+		t.Log("Expected armor without an END line to fail")
+		t.Fail()
+	}
+}
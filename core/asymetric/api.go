@@ -13,3 +13,15 @@ type LocalRSAContainer interface {
 	PublicKeyBytes() []byte
 	Decrypt(DecryptHandler, []byte)
 }
+
+type Signer interface {
+	Algorithm() string
+	PublicKeyBytes() []byte
+	Sign(message []byte) ([]byte, error)
+}
+
+type Verifier interface {
+	Algorithm() string
+	PublicKeyBytes() []byte
+	Verify(message []byte, signature []byte) error
+}
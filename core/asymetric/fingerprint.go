@@ -0,0 +1,23 @@
+package asymetric
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const (
+	OAEPStanzaType   = "rsa-oaep"
+	SignatureRSAPSS  = "rsa-pss-sha256"
+	SignatureEd25519 = "ed25519"
+)
+
+type OAEPStanzaParams struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Fingerprint identifies a public key by the SHA-256 of its PKIX encoding.
+func Fingerprint(publicKeyBytes []byte) string {
+	sum := sha256.Sum256(publicKeyBytes)
+
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,26 @@
+package authenticator
+
+import (
+	"time"
+)
+
+// Account keeps its key as an otpauth URI, the form it arrives in and the
+// form any other authenticator takes it back in.
+type Account struct {
+	Name  string    `json:"name"`
+	URI   string    `json:"uri"`
+	Added time.Time `json:"added"`
+}
+
+type Vault interface {
+	Accounts() []Account
+	Get(name string) (Account, bool)
+	Add(account Account) error
+	Rename(name string, newName string) error
+	Delete(name string) error
+	// Code returns the code to enter at at and how long it stays current.
+	// HOTP codes stay current until used, so for them remaining is zero and
+	// the stored counter moves on, which the caller persists by saving. A
+	// counter that has reached its maximum gives no more codes.
+	Code(name string, at time.Time) (code string, remaining time.Duration, err error)
+}
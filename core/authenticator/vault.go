@@ -0,0 +1,183 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/otp"
+)
+
+type document struct {
+	Version  int       `json:"version"`
+	Accounts []Account `json:"accounts"`
+}
+
+type vault struct {
+	accounts map[string]Account
+}
+
+func (vault *vault) Accounts() []Account {
+	accounts := []Account{}
+	for _, account := range vault.accounts {
+		accounts = append(accounts, account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+
+	return accounts
+}
+
+func (vault *vault) Get(name string) (Account, bool) {
+	account, found := vault.accounts[name]
+	return account, found
+}
+
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n")
+}
+
+func (vault *vault) checkNew(name string) error {
+	if !validName(name) {
+		return fmt.Errorf("account name [%s] must be non empty and contain no whitespace", name)
+	}
+
+	if _, found := vault.accounts[name]; found {
+		return fmt.Errorf("an account named [%s] already exists", name)
+	}
+
+	return nil
+}
+
+func (vault *vault) Add(account Account) error {
+	if err := vault.checkNew(account.Name); err != nil {
+		return err
+	}
+
+	if _, err := otp.ParseURI(account.URI); err != nil {
+		return err
+	}
+
+	vault.accounts[account.Name] = account
+	return nil
+}
+
+func (vault *vault) Rename(name string, newName string) error {
+	account, found := vault.accounts[name]
+	if !found {
+		return fmt.Errorf("there is no account named [%s]", name)
+	}
+
+	if err := vault.checkNew(newName); err != nil {
+		return err
+	}
+
+	delete(vault.accounts, name)
+	account.Name = newName
+	vault.accounts[newName] = account
+
+	return nil
+}
+
+func (vault *vault) Delete(name string) error {
+	if _, found := vault.accounts[name]; !found {
+		return fmt.Errorf("there is no account named [%s]", name)
+	}
+
+	delete(vault.accounts, name)
+	return nil
+}
+
+func (vault *vault) Code(name string, at time.Time) (string, time.Duration, error) {
+	account, found := vault.accounts[name]
+	if !found {
+		return "", 0, fmt.Errorf("there is no account named [%s]", name)
+	}
+
+	key, err := otp.ParseURI(account.URI)
+	if err != nil {
+		return "", 0, err
+	}
+
+	generator, err := key.Generator()
+	if err != nil {
+		return "", 0, err
+	}
+
+	if key.Type == otp.TypeTOTP {
+		return generator.TOTP(at), generator.Remaining(at), nil
+	}
+
+	// Wrapping around would hand out the codes of counter zero onwards again.
+	if key.Counter == math.MaxUint64 {
+		return "", 0, fmt.Errorf("the HOTP counter of [%s] is used up, enroll a new secret", name)
+	}
+
+	code := generator.HOTP(key.Counter)
+	key.Counter++
+	account.URI = key.URI()
+	vault.accounts[name] = account
+
+	return code, 0, nil
+}
+
+func New() Vault {
+	return &vault{accounts: map[string]Account{}}
+}
+
+func Load(src io.Reader, identities ...fileformat.Identity) (Vault, error) {
+	reader, err := fileformat.NewReader(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded document
+	if err = json.Unmarshal(contents, &loaded); err != nil {
+		return nil, fmt.Errorf("could not parse vault: %w", err)
+	}
+
+	if loaded.Version != 1 {
+		return nil, fmt.Errorf("unsupported vault version [%d]", loaded.Version)
+	}
+
+	vault := &vault{accounts: map[string]Account{}}
+	for _, account := range loaded.Accounts {
+		vault.accounts[account.Name] = account
+	}
+
+	return vault, nil
+}
+
+func Save(dst io.Writer, vault Vault, recipients ...fileformat.Recipient) error {
+	contents, err := json.Marshal(document{
+		Version:  1,
+		Accounts: vault.Accounts(),
+	})
+	if err != nil {
+		return err
+	}
+
+	writer, err := fileformat.NewWriter(dst, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(contents); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
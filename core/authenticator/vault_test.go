@@ -0,0 +1,155 @@
+package authenticator_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/authenticator"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+// The RFC 4226 seed, "12345678901234567890", in Base32.
+const (
+	totpURI = "otpauth://totp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example"
+	hotpURI = "otpauth://hotp/Example:bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=2"
+)
+
+func newTestVault(t *testing.T) authenticator.Vault {
+	vault := authenticator.New()
+
+	for name, uri := range map[string]string{"alice": totpURI, "bob": hotpURI} {
+		if err := vault.Add(authenticator.Account{Name: name, URI: uri, Added: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+			t.Log("Add failed", err)
+			t.FailNow()
+		}
+	}
+
+	return vault
+}
+
+func saveAndLoad(t *testing.T, vault authenticator.Vault) authenticator.Vault {
+	saved := bytes.NewBuffer(nil)
+	if err := authenticator.Save(saved, vault, testkeys.NewRecipient(7)); err != nil {
+		t.Log("Save failed", err)
+		t.FailNow()
+	}
+
+	if bytes.Contains(saved.Bytes(), []byte("GEZDGNBV")) {
+		t.Log("Expected the saved vault not to contain the secret")
+		t.FailNow()
+	}
+
+	loaded, err := authenticator.Load(saved, testkeys.NewIdentity(7))
+	if err != nil {
+		t.Log("Load failed", err)
+		t.FailNow()
+	}
+
+	return loaded
+}
+
+func Test_TOTPCodesAndRemainingTime(t *testing.T) {
+	vault := saveAndLoad(t, newTestVault(t))
+
+	code, remaining, err := vault.Code("alice", time.Unix(1111111109, 0))
+	if err != nil || code != "081804" || remaining != time.Second {
+		t.Log("Unexpected code", code, remaining, err)
+		t.Fail()
+	}
+}
+
+func Test_HOTPCounterPersists(t *testing.T) {
+	vault := newTestVault(t)
+
+	code, _, err := vault.Code("bob", time.Now())
+	if err != nil || code != "359152" {
+		t.Log("Expected the code for counter 2, received", code, err)
+		t.FailNow()
+	}
+
+	loaded := saveAndLoad(t, vault)
+
+	if code, _, err = loaded.Code("bob", time.Now()); err != nil || code != "969429" {
+		t.Log("Expected the code for counter 3 after loading, received", code, err)
+		t.Fail()
+	}
+}
+
+func Test_HOTPCounterDoesNotWrap(t *testing.T) {
+	vault := authenticator.New()
+	uri := "otpauth://hotp/Example:carol?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=18446744073709551614"
+	if err := vault.Add(authenticator.Account{Name: "carol", URI: uri}); err != nil {
+		t.Log("Add failed", err)
+		t.FailNow()
+	}
+
+	if _, _, err := vault.Code("carol", time.Now()); err != nil {
+		t.Log("Expected the last counter to give a code, received", err)
+		t.FailNow()
+	}
+
+	used, _ := vault.Get("carol")
+	for i := 0; i < 2; i++ {
+		if code, _, err := vault.Code("carol", time.Now()); err == nil {
+			t.Log("Expected the used up counter to be refused, received", code)
+			t.Fail()
+		}
+	}
+
+	if account, _ := vault.Get("carol"); account.URI != used.URI {
+		t.Log("Expected the counter to stay put, received", account.URI)
+		t.Fail()
+	}
+}
+
+func Test_RenameAndDelete(t *testing.T) {
+	vault := newTestVault(t)
+
+	if err := vault.Rename("alice", "bob"); err == nil {
+		t.Log("Expected renaming onto an existing account to fail")
+		t.Fail()
+	}
+
+	if err := vault.Rename("alice", "work"); err != nil {
+		t.Log("Rename failed", err)
+		t.FailNow()
+	}
+
+	if _, found := vault.Get("alice"); found {
+		t.Log("Expected the old name to be gone")
+		t.Fail()
+	}
+
+	if account, found := vault.Get("work"); !found || account.URI != totpURI {
+		t.Log("Expected the account under its new name")
+		t.Fail()
+	}
+
+	if err := vault.Delete("work"); err != nil {
+		t.Log("Delete failed", err)
+		t.Fail()
+	}
+
+	if accounts := vault.Accounts(); len(accounts) != 1 || accounts[0].Name != "bob" {
+		t.Log("Unexpected accounts", accounts)
+		t.Fail()
+	}
+}
+
+func Test_InvalidAccountsAreRejected(t *testing.T) {
+	vault := newTestVault(t)
+
+	invalid := []authenticator.Account{
+		{Name: "alice", URI: totpURI},
+		{Name: "has space", URI: totpURI},
+		{Name: "broken", URI: "otpauth://totp/x"},
+	}
+
+	for _, account := range invalid {
+		if err := vault.Add(account); err == nil {
+			t.Log("Expected", account.Name, "to be rejected")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	TypeDirectory = "dir"
+	TypeFile      = "file"
+
+	// Latest names the most recent snapshot.
+	Latest = "latest"
+)
+
+// ChunkParams bound the size of chunks in bytes. Boundaries fall where the
+// content says so, so an insertion early in a file only changes the chunks
+// around it.
+type ChunkParams struct {
+	Min     int `json:"min"`
+	Average int `json:"average"`
+	Max     int `json:"max"`
+}
+
+var DefaultChunkParams = ChunkParams{Min: 256 * 1024, Average: 1024 * 1024, Max: 4 * 1024 * 1024}
+
+type Entry struct {
+	Path    string      `json:"path"`
+	Type    string      `json:"type"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+	Size    int64       `json:"size,omitempty"`
+	Chunks  []string    `json:"chunks,omitempty"`
+}
+
+type Snapshot struct {
+	Id      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Root    string    `json:"root"`
+	Entries []Entry   `json:"entries"`
+}
+
+type Stats struct {
+	Files int
+	// UnchangedFiles kept their size, mode and mtime since the previous
+	// snapshot of the same directory and were not read again.
+	UnchangedFiles int
+	Bytes          int64
+	NewChunks      int
+	NewBytes       int64
+}
+
+type Chunker interface {
+	// Next returns the next chunk, or io.EOF after the last one. The chunk
+	// is only valid until the following call.
+	Next() ([]byte, error)
+}
+
+type Repository interface {
+	Backup(root string, onSkip func(path string, reason string)) (Snapshot, Stats, error)
+	// Snapshots are ordered oldest first.
+	Snapshots() ([]Snapshot, error)
+	// Snapshot accepts a snapshot id or Latest.
+	Snapshot(id string) (Snapshot, error)
+	Open(entry Entry) io.Reader
+	Restore(entry Entry, dir string) error
+	RestoreAll(snapshot Snapshot, dir string) error
+}
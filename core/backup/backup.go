@@ -0,0 +1,182 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// previousSnapshot finds the newest snapshot of root, whose files need not
+// be read again when they look unchanged.
+func (repository *repository) previousSnapshot(root string) (map[string]Entry, error) {
+	ids, err := repository.snapshotIds()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]Entry{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		snapshot, err := repository.readSnapshot(ids[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if snapshot.Root != root {
+			continue
+		}
+
+		for _, entry := range snapshot.Entries {
+			if entry.Type == TypeFile {
+				entries[entry.Path] = entry
+			}
+		}
+
+		break
+	}
+
+	return entries, nil
+}
+
+func (repository *repository) unchanged(previous Entry, found bool, entry Entry) bool {
+	if !found || previous.Size != entry.Size || previous.Mode != entry.Mode || !previous.ModTime.Equal(entry.ModTime) {
+		return false
+	}
+
+	for _, id := range previous.Chunks {
+		if !repository.hasChunk(id) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// backupFile records the size that was actually read, so a file that is
+// written to during the backup is kept as it was read rather than failing
+// the whole run.
+func (repository *repository) backupFile(path string, entry *Entry, stats *Stats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	chunker, err := NewChunker(file, repository.chunking, repository.chunkerSeed)
+	if err != nil {
+		return err
+	}
+
+	entry.Size = 0
+	entry.Chunks = []string{}
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read [%s]: %w", path, err)
+		}
+
+		id, isNew, err := repository.storeChunk(chunk)
+		if err != nil {
+			return err
+		}
+
+		if isNew {
+			stats.NewChunks++
+			stats.NewBytes += int64(len(chunk))
+		}
+
+		entry.Size += int64(len(chunk))
+		entry.Chunks = append(entry.Chunks, id)
+	}
+}
+
+// Backup stores every regular file and directory under root. Chunks are
+// written before the snapshot that references them, so an interrupted
+// backup leaves at worst some unreferenced chunks. A repository inside root
+// is left out, it would otherwise grow by its own chunks on every run.
+func (repository *repository) Backup(root string, onSkip func(path string, reason string)) (Snapshot, Stats, error) {
+	stats := Stats{}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return Snapshot{}, stats, err
+	}
+
+	previous, err := repository.previousSnapshot(root)
+	if err != nil {
+		return Snapshot{}, stats, err
+	}
+
+	id, err := newSnapshotId()
+	if err != nil {
+		return Snapshot{}, stats, err
+	}
+
+	repositoryInfo, err := os.Stat(repository.dir)
+	if err != nil {
+		return Snapshot{}, stats, err
+	}
+
+	snapshot := Snapshot{Id: id, Root: root, Entries: []Entry{}}
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		if relative == "." {
+			return nil
+		}
+
+		if info.IsDir() && os.SameFile(info, repositoryInfo) {
+			onSkip(path, "the backup repository")
+			return filepath.SkipDir
+		}
+
+		entry := Entry{
+			Path:    filepath.ToSlash(relative),
+			Mode:    info.Mode().Perm(),
+			ModTime: info.ModTime(),
+		}
+
+		switch {
+		case info.IsDir():
+			entry.Type = TypeDirectory
+		case info.Mode().IsRegular():
+			entry.Type = TypeFile
+			entry.Size = info.Size()
+			stats.Files++
+
+			if last, found := previous[entry.Path]; repository.unchanged(last, found, entry) {
+				entry.Chunks = last.Chunks
+				stats.UnchangedFiles++
+			} else if err = repository.backupFile(path, &entry, &stats); err != nil {
+				return err
+			}
+
+			stats.Bytes += entry.Size
+		default:
+			onSkip(path, fmt.Sprintf("unsupported file type [%s]", info.Mode()&os.ModeType))
+			return nil
+		}
+
+		snapshot.Entries = append(snapshot.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return Snapshot{}, stats, err
+	}
+
+	snapshot.Time = time.Now().UTC()
+
+	return snapshot, stats, repository.writeSnapshot(snapshot)
+}
@@ -0,0 +1,298 @@
+package backup_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/backup"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+var testChunking = backup.ChunkParams{Min: 1024, Average: 4096, Max: 16384}
+
+func randomBytes(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+
+	return data
+}
+
+func chunksOf(t *testing.T, data []byte) [][]byte {
+	chunker, err := backup.NewChunker(bytes.NewReader(data), testChunking, []byte("seed"))
+	if err != nil {
+		t.Log("NewChunker failed", err)
+		t.FailNow()
+	}
+
+	chunks := [][]byte{}
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Log("Next failed", err)
+			t.FailNow()
+		}
+
+		chunks = append(chunks, append([]byte{}, chunk...))
+	}
+}
+
+func tempDir(t *testing.T, prefix string) string {
+	dir, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		t.Log("TempDir failed", err)
+		t.FailNow()
+	}
+
+	return dir
+}
+
+func writeTestFile(t *testing.T, path string, contents []byte, mode os.FileMode) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Log("MkdirAll failed", err)
+		t.FailNow()
+	}
+
+	if err := ioutil.WriteFile(path, contents, mode); err != nil {
+		t.Log("WriteFile failed", err)
+		t.FailNow()
+	}
+
+	modTime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	os.Chmod(path, mode)
+	os.Chtimes(path, modTime, modTime)
+}
+
+func newTestTree(t *testing.T) string {
+	root := tempDir(t, "backup-source")
+
+	writeTestFile(t, filepath.Join(root, "large.bin"), randomBytes(1, 200*1024), 0644)
+	writeTestFile(t, filepath.Join(root, "nested", "script.sh"), []byte("#!/bin/sh\necho hi\n"), 0755)
+	writeTestFile(t, filepath.Join(root, "nested", "empty"), nil, 0600)
+
+	return root
+}
+
+func newTestRepository(t *testing.T) (backup.Repository, string) {
+	dir := tempDir(t, "backup-repository")
+
+	repository, err := backup.Init(dir, testChunking, testkeys.NewRecipient(7))
+	if err != nil {
+		t.Log("Init failed", err)
+		t.FailNow()
+	}
+
+	return repository, dir
+}
+
+func backupForTest(t *testing.T, repository backup.Repository, root string) (backup.Snapshot, backup.Stats) {
+	snapshot, stats, err := repository.Backup(root, func(path string, reason string) {
+		t.Log("Unexpected skip of", path, reason)
+		t.Fail()
+	})
+	if err != nil {
+		t.Log("Backup failed", err)
+		t.FailNow()
+	}
+
+	return snapshot, stats
+}
+
+func Test_ChunksReassembleWithinBounds(t *testing.T) {
+	data := randomBytes(2, 300*1024)
+	chunks := chunksOf(t, data)
+
+	if !bytes.Equal(bytes.Join(chunks, nil), data) {
+		t.Log("Chunks do not reassemble the input")
+		t.FailNow()
+	}
+
+	for i, chunk := range chunks {
+		if len(chunk) > testChunking.Max || (i < len(chunks)-1 && len(chunk) < testChunking.Min) {
+			t.Log("Chunk", i, "has size", len(chunk))
+			t.Fail()
+		}
+	}
+}
+
+func Test_InsertionOnlyChangesNearbyChunks(t *testing.T) {
+	data := randomBytes(3, 300*1024)
+	original := chunksOf(t, data)
+
+	edited := append(append([]byte("a few inserted bytes"), data[:1000]...), data[1000:]...)
+	seen := map[string]bool{}
+	for _, chunk := range original {
+		seen[string(chunk)] = true
+	}
+
+	changed := 0
+	for _, chunk := range chunksOf(t, edited) {
+		if !seen[string(chunk)] {
+			changed++
+		}
+	}
+
+	if changed > 2 {
+		t.Log("Expected the insertion to change at most 2 of", len(original), "chunks, changed", changed)
+		t.Fail()
+	}
+}
+
+func Test_CanBackupAndRestore(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+	repository, dir := newTestRepository(t)
+	defer os.RemoveAll(dir)
+
+	backupForTest(t, repository, root)
+
+	reopened, err := backup.Open(dir, testkeys.NewIdentity(7))
+	if err != nil {
+		t.Log("Open failed", err)
+		t.FailNow()
+	}
+
+	snapshot, err := reopened.Snapshot(backup.Latest)
+	if err != nil {
+		t.Log("Snapshot failed", err)
+		t.FailNow()
+	}
+
+	target := tempDir(t, "backup-target")
+	defer os.RemoveAll(target)
+
+	if err = reopened.RestoreAll(snapshot, target); err != nil {
+		t.Log("RestoreAll failed", err)
+		t.FailNow()
+	}
+
+	for _, name := range []string{"large.bin", "nested/script.sh", "nested/empty"} {
+		expected, _ := ioutil.ReadFile(filepath.Join(root, name))
+		actual, err := ioutil.ReadFile(filepath.Join(target, name))
+		if err != nil || !bytes.Equal(expected, actual) {
+			t.Log("Restored", name, "differs", err)
+			t.Fail()
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(target, "nested", "script.sh")); err != nil || info.Mode().Perm() != 0755 {
+		t.Log("Expected the mode to be restored", err)
+		t.Fail()
+	}
+}
+
+func Test_UnchangedFilesAreNotStoredAgain(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+	repository, dir := newTestRepository(t)
+	defer os.RemoveAll(dir)
+
+	_, first := backupForTest(t, repository, root)
+	if first.NewChunks == 0 || first.UnchangedFiles != 0 {
+		t.Log("Unexpected first backup", first)
+		t.Fail()
+	}
+
+	_, second := backupForTest(t, repository, root)
+	if second.NewChunks != 0 || second.UnchangedFiles != second.Files {
+		t.Log("Expected an unchanged tree to cost nothing", second)
+		t.Fail()
+	}
+
+	edited := append([]byte("prepended"), randomBytes(1, 200*1024)...)
+	writeTestFile(t, filepath.Join(root, "large.bin"), edited, 0644)
+
+	_, third := backupForTest(t, repository, root)
+	if third.NewChunks == 0 || third.NewBytes > int64(2*testChunking.Max) {
+		t.Log("Expected only the chunks around the edit to be stored", third)
+		t.Fail()
+	}
+
+	snapshots, err := repository.Snapshots()
+	if err != nil || len(snapshots) != 3 {
+		t.Log("Expected three snapshots", len(snapshots), err)
+		t.Fail()
+	}
+}
+
+func Test_WrongIdentityCannotOpen(t *testing.T) {
+	_, dir := newTestRepository(t)
+	defer os.RemoveAll(dir)
+
+	if _, err := backup.Open(dir, testkeys.NewIdentity(8)); err == nil {
+		t.Log("Expected the wrong identity to fail")
+		t.Fail()
+	}
+}
+
+func Test_TamperedChunkFailsRestore(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+	repository, dir := newTestRepository(t)
+	defer os.RemoveAll(dir)
+
+	snapshot, _ := backupForTest(t, repository, root)
+
+	for _, entry := range snapshot.Entries {
+		if entry.Path != "large.bin" {
+			continue
+		}
+
+		path := filepath.Join(dir, "chunks", entry.Chunks[0][:2], entry.Chunks[0])
+		sealed, _ := ioutil.ReadFile(path)
+		sealed[len(sealed)-1] ^= 1
+		ioutil.WriteFile(path, sealed, 0600)
+
+		if _, err := ioutil.ReadAll(repository.Open(entry)); err == nil {
+			t.Log("Expected the tampered chunk to fail")
+			t.Fail()
+		}
+
+		return
+	}
+
+	t.Log("large.bin is missing from the snapshot")
+	t.Fail()
+}
+
+func Test_RepositoryInsideRootIsSkipped(t *testing.T) {
+	root := newTestTree(t)
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "repository")
+	repository, err := backup.Init(dir, testChunking, testkeys.NewRecipient(7))
+	if err != nil {
+		t.Log("Init failed", err)
+		t.FailNow()
+	}
+
+	skipped := []string{}
+	snapshot, _, err := repository.Backup(root, func(path string, reason string) {
+		skipped = append(skipped, path)
+	})
+	if err != nil {
+		t.Log("Backup failed", err)
+		t.FailNow()
+	}
+
+	if len(skipped) != 1 || skipped[0] != dir {
+		t.Log("Expected only the repository to be skipped, received", skipped)
+		t.Fail()
+	}
+
+	for _, entry := range snapshot.Entries {
+		if strings.HasPrefix(entry.Path, "repository") {
+			t.Log("Expected the repository not to be backed up, found", entry.Path)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+func (params ChunkParams) validate() error {
+	if params.Min <= 0 || params.Min > params.Average || params.Average > params.Max {
+		return fmt.Errorf("chunk sizes must satisfy 0 < min <= average <= max, received [%d] [%d] [%d]", params.Min, params.Average, params.Max)
+	}
+
+	return nil
+}
+
+type chunker struct {
+	src    io.Reader
+	params ChunkParams
+	gear   [256]uint64
+	mask   uint64
+	buffer []byte
+	start  int
+	end    int
+	eof    bool
+}
+
+// NewChunker splits src with a gear rolling hash. The gear table comes from
+// seed, so with a secret seed the chunk sizes reveal nothing about the
+// content.
+func NewChunker(src io.Reader, params ChunkParams, seed []byte) (Chunker, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	chunker := &chunker{
+		src:    src,
+		params: params,
+		buffer: make([]byte, params.Max),
+	}
+
+	for i := range chunker.gear {
+		sum := sha256.Sum256(append(append([]byte{}, seed...), byte(i)))
+		chunker.gear[i] = binary.BigEndian.Uint64(sum[:8])
+	}
+
+	// Each bit of the gear hash depends on one more byte than the bit below
+	// it, so boundaries are picked from the top bits, which see the most.
+	chunker.mask = ^uint64(0) << (64 - (bits.Len(uint(params.Average)) - 1))
+
+	return chunker, nil
+}
+
+func (chunker *chunker) fill() error {
+	copy(chunker.buffer, chunker.buffer[chunker.start:chunker.end])
+	chunker.end -= chunker.start
+	chunker.start = 0
+
+	for !chunker.eof && chunker.end < len(chunker.buffer) {
+		n, err := chunker.src.Read(chunker.buffer[chunker.end:])
+		chunker.end += n
+
+		if err == io.EOF {
+			chunker.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (chunker *chunker) boundary(data []byte) int {
+	if len(data) <= chunker.params.Min {
+		return len(data)
+	}
+
+	hash := uint64(0)
+	for i := chunker.params.Min; i < len(data); i++ {
+		hash = hash<<1 + chunker.gear[data[i]]
+		if hash&chunker.mask == 0 {
+			return i + 1
+		}
+	}
+
+	return len(data)
+}
+
+func (chunker *chunker) Next() ([]byte, error) {
+	if chunker.end-chunker.start < chunker.params.Max && !chunker.eof {
+		if err := chunker.fill(); err != nil {
+			return nil, err
+		}
+	}
+
+	available := chunker.buffer[chunker.start:chunker.end]
+	if len(available) == 0 {
+		return nil, io.EOF
+	}
+
+	chunk := available[:chunker.boundary(available)]
+	chunker.start += len(chunk)
+
+	return chunk, nil
+}
@@ -0,0 +1,387 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	currentVersion = 1
+	keySize        = 32
+
+	configName   = "config"
+	chunksDir    = "chunks"
+	snapshotsDir = "snapshots"
+
+	chunkCipher = fileformat.CipherXChaCha20Poly1305
+)
+
+// config is kept as an ordinary encrypto file, so the repository opens with
+// any identity and recipients can be changed with the recipients command.
+type config struct {
+	Version  int         `json:"version"`
+	Key      []byte      `json:"key"`
+	Chunking ChunkParams `json:"chunking"`
+}
+
+type repository struct {
+	dir         string
+	chunking    ChunkParams
+	idKey       []byte
+	chunkerSeed []byte
+	aead        cipher.AEAD
+}
+
+func deriveKey(key []byte, info string) ([]byte, error) {
+	derived := make([]byte, keySize)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(info)), derived); err != nil {
+		return nil, err
+	}
+
+	return derived, nil
+}
+
+func newRepository(dir string, config config) (*repository, error) {
+	if config.Version != currentVersion {
+		return nil, fmt.Errorf("unsupported repository version [%d]", config.Version)
+	}
+
+	if len(config.Key) != keySize {
+		return nil, errors.New("the repository key is damaged")
+	}
+
+	if err := config.Chunking.validate(); err != nil {
+		return nil, err
+	}
+
+	repository := &repository{dir: dir, chunking: config.Chunking}
+
+	var err error
+	if repository.idKey, err = deriveKey(config.Key, "encrypto backup chunk id"); err != nil {
+		return nil, err
+	}
+
+	if repository.chunkerSeed, err = deriveKey(config.Key, "encrypto backup chunker"); err != nil {
+		return nil, err
+	}
+
+	encryptionKey, err := deriveKey(config.Key, "encrypto backup encryption")
+	if err != nil {
+		return nil, err
+	}
+
+	if repository.aead, err = fileformat.NewAEAD(chunkCipher, encryptionKey); err != nil {
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+// writeAtomically never leaves a partial file under path, so an interrupted
+// backup cannot leave a chunk behind that later backups would trust.
+func writeAtomically(path string, data []byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+
+	return err
+}
+
+// Init creates an empty repository in dir, which must not hold one yet.
+func Init(dir string, chunking ChunkParams, recipients ...fileformat.Recipient) (Repository, error) {
+	if _, err := os.Stat(filepath.Join(dir, configName)); err == nil {
+		return nil, fmt.Errorf("[%s] already holds a repository", dir)
+	}
+
+	config := config{Version: currentVersion, Key: make([]byte, keySize), Chunking: chunking}
+	if _, err := io.ReadFull(rand.Reader, config.Key); err != nil {
+		return nil, err
+	}
+
+	repository, err := newRepository(dir, config)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, subdir := range []string{chunksDir, snapshotsDir} {
+		if err = os.MkdirAll(filepath.Join(dir, subdir), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := bytes.NewBuffer(nil)
+	writer, err := fileformat.NewWriter(sealed, recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = writer.Write(configJson); err != nil {
+		return nil, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if err = writeAtomically(filepath.Join(dir, configName), sealed.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+func Open(dir string, identities ...fileformat.Identity) (Repository, error) {
+	file, err := os.Open(filepath.Join(dir, configName))
+	if err != nil {
+		return nil, fmt.Errorf("[%s] is not a backup repository: %w", dir, err)
+	}
+	defer file.Close()
+
+	reader, err := fileformat.NewReader(file, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	configJson, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var config config
+	if err = json.Unmarshal(configJson, &config); err != nil {
+		return nil, fmt.Errorf("could not parse the repository config: %w", err)
+	}
+
+	return newRepository(dir, config)
+}
+
+func (repository *repository) seal(plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, repository.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return repository.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (repository *repository) open(sealed []byte, additionalData []byte) ([]byte, error) {
+	nonceSize := repository.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("truncated")
+	}
+
+	return repository.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+}
+
+// chunkId is keyed, so the names in the repository do not give away which
+// well known files it holds.
+func (repository *repository) chunkId(chunk []byte) string {
+	mac := hmac.New(sha256.New, repository.idKey)
+	mac.Write(chunk)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (repository *repository) chunkPath(id string) (string, error) {
+	if decoded, err := hex.DecodeString(id); err != nil || len(decoded) != sha256.Size {
+		return "", fmt.Errorf("invalid chunk id [%s]", id)
+	}
+
+	return filepath.Join(repository.dir, chunksDir, id[:2], id), nil
+}
+
+func (repository *repository) hasChunk(id string) bool {
+	path, err := repository.chunkPath(id)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// storeChunk reports whether the chunk was new to the repository.
+func (repository *repository) storeChunk(chunk []byte) (string, bool, error) {
+	id := repository.chunkId(chunk)
+	if repository.hasChunk(id) {
+		return id, false, nil
+	}
+
+	path, err := repository.chunkPath(id)
+	if err != nil {
+		return "", false, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", false, err
+	}
+
+	// The id is the associated data, so a chunk cannot be passed off under
+	// another chunk's name.
+	sealed, err := repository.seal(chunk, []byte(id))
+	if err != nil {
+		return "", false, err
+	}
+
+	return id, true, writeAtomically(path, sealed)
+}
+
+func (repository *repository) readChunk(id string) ([]byte, error) {
+	path, err := repository.chunkPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("missing chunk [%s]: %w", id, err)
+	}
+
+	chunk, err := repository.open(sealed, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("chunk [%s] is damaged", id)
+	}
+
+	return chunk, nil
+}
+
+func newSnapshotId() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := io.ReadFull(rand.Reader, suffix); err != nil {
+		return "", err
+	}
+
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix), nil
+}
+
+func snapshotData(id string) []byte {
+	return []byte("encrypto backup snapshot " + id)
+}
+
+func (repository *repository) writeSnapshot(snapshot Snapshot) error {
+	snapshotJson, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	sealed, err := repository.seal(snapshotJson, snapshotData(snapshot.Id))
+	if err != nil {
+		return err
+	}
+
+	return writeAtomically(filepath.Join(repository.dir, snapshotsDir, snapshot.Id), sealed)
+}
+
+// snapshotIds sort oldest first, since every id starts with its time.
+func (repository *repository) snapshotIds() ([]string, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(repository.dir, snapshotsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && !strings.HasPrefix(info.Name(), ".") {
+			ids = append(ids, info.Name())
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (repository *repository) readSnapshot(id string) (Snapshot, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return Snapshot{}, fmt.Errorf("invalid snapshot id [%s]", id)
+	}
+
+	sealed, err := ioutil.ReadFile(filepath.Join(repository.dir, snapshotsDir, id))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("no snapshot [%s]", id)
+	}
+
+	snapshotJson, err := repository.open(sealed, snapshotData(id))
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("snapshot [%s] is damaged", id)
+	}
+
+	var snapshot Snapshot
+	if err = json.Unmarshal(snapshotJson, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("could not parse snapshot [%s]: %w", id, err)
+	}
+
+	return snapshot, nil
+}
+
+func (repository *repository) Snapshots() ([]Snapshot, error) {
+	ids, err := repository.snapshotIds()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, id := range ids {
+		snapshot, err := repository.readSnapshot(id)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+func (repository *repository) Snapshot(id string) (Snapshot, error) {
+	if id != Latest {
+		return repository.readSnapshot(id)
+	}
+
+	ids, err := repository.snapshotIds()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if len(ids) == 0 {
+		return Snapshot{}, errors.New("the repository has no snapshots")
+	}
+
+	return repository.readSnapshot(ids[len(ids)-1])
+}
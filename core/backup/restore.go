@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+
+	"util.tim/encrypto/core/internal/extract"
+)
+
+// chunkReader decrypts the chunks of a file one at a time as they are read.
+type chunkReader struct {
+	repository *repository
+	ids        []string
+	current    []byte
+	err        error
+}
+
+func (reader *chunkReader) Read(p []byte) (int, error) {
+	for len(reader.current) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+
+		if len(reader.ids) == 0 {
+			return 0, io.EOF
+		}
+
+		reader.current, reader.err = reader.repository.readChunk(reader.ids[0])
+		reader.ids = reader.ids[1:]
+	}
+
+	n := copy(p, reader.current)
+	reader.current = reader.current[n:]
+
+	return n, nil
+}
+
+func (repository *repository) Open(entry Entry) io.Reader {
+	return &chunkReader{repository: repository, ids: entry.Chunks}
+}
+
+// toExtract checks the entry type, since extract only knows directories and
+// files, and that each restored file has the size the snapshot records.
+func (repository *repository) toExtract(entry Entry) (extract.Entry, error) {
+	if entry.Type != TypeFile && entry.Type != TypeDirectory {
+		return extract.Entry{}, fmt.Errorf("unknown entry type [%s] for [%s]", entry.Type, entry.Path)
+	}
+
+	return extract.Entry{
+		Path:      entry.Path,
+		Directory: entry.Type == TypeDirectory,
+		Mode:      entry.Mode,
+		ModTime:   entry.ModTime,
+		Write: func(dst io.Writer) error {
+			written, err := io.Copy(dst, repository.Open(entry))
+			if err == nil && written != entry.Size {
+				err = fmt.Errorf("restored [%d] bytes of [%s] but the snapshot records [%d]", written, entry.Path, entry.Size)
+			}
+
+			return err
+		},
+	}, nil
+}
+
+func (repository *repository) Restore(entry Entry, dir string) error {
+	converted, err := repository.toExtract(entry)
+	if err != nil {
+		return err
+	}
+
+	return extract.Extract(converted, dir)
+}
+
+func (repository *repository) RestoreAll(snapshot Snapshot, dir string) error {
+	entries := []extract.Entry{}
+	for _, entry := range snapshot.Entries {
+		converted, err := repository.toExtract(entry)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, converted)
+	}
+
+	return extract.ExtractAll(entries, dir)
+}
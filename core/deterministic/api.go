@@ -0,0 +1,118 @@
+package deterministic
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Deterministic authenticated encryption in the SIV style: the IV is a MAC
+// of the plaintext and the plaintext is encrypted with AES-CTR under that
+// IV. Equal plaintexts give equal ciphertexts, which is the point for
+// version control, and decryption recomputes the MAC to authenticate.
+// The only thing an observer learns is whether two contents are equal.
+
+const (
+	KeySize = 32
+	ivSize  = aes.BlockSize
+	version = 1
+)
+
+var magic = []byte("\x00ENCRYPTO-DET\x00")
+
+var ErrNotEncrypted = errors.New("input is not deterministically encrypted")
+
+func deriveKeys(key []byte) ([]byte, []byte, error) {
+	if len(key) != KeySize {
+		return nil, nil, errors.New("deterministic keys must be 32 bytes")
+	}
+
+	derived := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte("encrypto deterministic")), derived); err != nil {
+		return nil, nil, err
+	}
+
+	return derived[:32], derived[32:], nil
+}
+
+func syntheticIV(macKey []byte, plaintext []byte) []byte {
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(magic)
+	mac.Write([]byte{version})
+	mac.Write(plaintext)
+
+	return mac.Sum(nil)[:ivSize]
+}
+
+func ctr(encryptionKey []byte, iv []byte, dst []byte, src []byte) error {
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+	return nil
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	encryptionKey, macKey, err := deriveKeys(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := syntheticIV(macKey, plaintext)
+
+	out := make([]byte, len(magic)+1+ivSize+len(plaintext))
+	copy(out, magic)
+	out[len(magic)] = version
+	copy(out[len(magic)+1:], iv)
+
+	if err = ctr(encryptionKey, iv, out[len(magic)+1+ivSize:], plaintext); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	if !IsEncrypted(ciphertext) {
+		return nil, ErrNotEncrypted
+	}
+
+	if len(ciphertext) < len(magic)+1+ivSize {
+		return nil, errors.New("deterministic ciphertext is truncated")
+	}
+
+	if ciphertext[len(magic)] != version {
+		return nil, errors.New("unsupported deterministic ciphertext version")
+	}
+
+	encryptionKey, macKey, err := deriveKeys(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := ciphertext[len(magic)+1 : len(magic)+1+ivSize]
+	body := ciphertext[len(magic)+1+ivSize:]
+
+	plaintext := make([]byte, len(body))
+	if err = ctr(encryptionKey, iv, plaintext, body); err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(syntheticIV(macKey, plaintext), iv) {
+		return nil, errors.New("deterministic ciphertext failed authentication")
+	}
+
+	return plaintext, nil
+}
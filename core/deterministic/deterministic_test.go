@@ -0,0 +1,75 @@
+package deterministic_test
+
+import (
+	"bytes"
+	"testing"
+
+	"util.tim/encrypto/core/deterministic"
+)
+
+var key = bytes.Repeat([]byte{3}, deterministic.KeySize)
+
+func encryptForTest(t *testing.T, plaintext string) []byte {
+	encrypted, err := deterministic.Encrypt(key, []byte(plaintext))
+	if err != nil {
+		t.Log("Encrypt failed", err)
+		t.FailNow()
+	}
+
+	return encrypted
+}
+
+func Test_RoundTrips(t *testing.T) {
+	for _, plaintext := range []string{"", "a", "password = hunter2\n"} {
+		decrypted, err := deterministic.Decrypt(key, encryptForTest(t, plaintext))
+		if err != nil {
+			t.Log("Decrypt failed", err)
+			t.FailNow()
+		}
+
+		if string(decrypted) != plaintext {
+			t.Log("Unexpected plaintext " + string(decrypted))
+			t.Fail()
+		}
+	}
+}
+
+func Test_EqualContentsGiveEqualCiphertexts(t *testing.T) {
+	if !bytes.Equal(encryptForTest(t, "same"), encryptForTest(t, "same")) {
+		t.Log("Expected encryption to be deterministic")
+		t.Fail()
+	}
+
+	if bytes.Equal(encryptForTest(t, "same"), encryptForTest(t, "sane")) {
+		t.Log("Expected different contents to give different ciphertexts")
+		t.Fail()
+	}
+}
+
+func Test_TamperingAndWrongKeysFail(t *testing.T) {
+	encrypted := encryptForTest(t, "password = hunter2\n")
+
+	flipped := append([]byte{}, encrypted...)
+	flipped[len(flipped)-1] ^= 1
+	if _, err := deterministic.Decrypt(key, flipped); err == nil {
+		t.Log("Expected a flipped bit to fail")
+		t.Fail()
+	}
+
+	if _, err := deterministic.Decrypt(bytes.Repeat([]byte{4}, deterministic.KeySize), encrypted); err == nil {
+		t.Log("Expected the wrong key to fail")
+		t.Fail()
+	}
+}
+
+func Test_RecognisesCiphertext(t *testing.T) {
+	if !deterministic.IsEncrypted(encryptForTest(t, "x")) || deterministic.IsEncrypted([]byte("plain text")) {
+		t.Log("Expected IsEncrypted to tell ciphertext from plaintext")
+		t.Fail()
+	}
+
+	if _, err := deterministic.Decrypt(key, []byte("plain text")); err != deterministic.ErrNotEncrypted {
+		t.Log("Expected ErrNotEncrypted", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,125 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	MetadataKey = "encrypto"
+)
+
+type Document interface {
+	Encrypted() bool
+	// Seal encrypts every leaf value in place and records the stanzas and
+	// a MAC over the whole document under MetadataKey.
+	Seal(keys *Keys) error
+	// Open checks the MAC and decrypts every leaf value in place, returning
+	// the keys so the document can be sealed again for the same recipients.
+	Open(identities ...fileformat.Identity) (*Keys, error)
+	Encode() ([]byte, error)
+}
+
+type document struct {
+	root   *yaml.Node
+	format string
+}
+
+// FormatFor picks a format from a file extension, falling back to looking at
+// the first character so stdin works too.
+func FormatFor(path string, contents []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '{' {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
+
+func rejectAliases(node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode || node.Anchor != "" {
+		return fmt.Errorf("line [%d]: anchors and aliases are not supported", node.Line)
+	}
+
+	for _, child := range node.Content {
+		if err := rejectAliases(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func Parse(contents []byte, format string) (Document, error) {
+	if format != FormatJSON && format != FormatYAML {
+		return nil, fmt.Errorf("unsupported document format [%s]", format)
+	}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(contents, root); err != nil {
+		return nil, err
+	}
+
+	if root.Kind != yaml.DocumentNode || len(root.Content) != 1 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the document must be a single mapping")
+	}
+
+	if err := rejectAliases(root); err != nil {
+		return nil, err
+	}
+
+	return &document{root: root, format: format}, nil
+}
+
+func (document *document) mapping() *yaml.Node {
+	return document.root.Content[0]
+}
+
+func (document *document) metadataIndex() int {
+	content := document.mapping().Content
+	for i := 0; i+1 < len(content); i += 2 {
+		if content[i].Value == MetadataKey {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (document *document) Encrypted() bool {
+	return document.metadataIndex() >= 0
+}
+
+func (document *document) Encode() ([]byte, error) {
+	if document.format == FormatJSON {
+		return encodeJSON(document.mapping())
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(document.root); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
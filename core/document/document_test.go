@@ -0,0 +1,193 @@
+package document_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/document"
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+const yamlFixture = `# service configuration
+database:
+  host: db.internal
+  port: 5432
+  password: hunter2
+features:
+  - alpha
+  - true
+certificate: |
+  line one
+  line two
+`
+
+const jsonFixture = `{
+  "name": "api",
+  "replicas": 3,
+  "ratio": 0.25,
+  "debug": false,
+  "token": null,
+  "nested": {
+    "html": "<b>&</b>",
+    "empty": []
+  }
+}
+`
+
+func sealForTest(t *testing.T, contents string, format string) []byte {
+	doc, err := document.Parse([]byte(contents), format)
+	if err != nil {
+		t.Log("Parse failed", err)
+		t.FailNow()
+	}
+
+	keys, err := document.NewKeys(fileformat.CipherAESGCM, testkeys.NewRecipient(1))
+	if err != nil {
+		t.Log("NewKeys failed", err)
+		t.FailNow()
+	}
+
+	if err = doc.Seal(keys); err != nil {
+		t.Log("Seal failed", err)
+		t.FailNow()
+	}
+
+	encoded, err := doc.Encode()
+	if err != nil {
+		t.Log("Encode failed", err)
+		t.FailNow()
+	}
+
+	return encoded
+}
+
+func openForTest(encoded []byte, format string) ([]byte, error) {
+	doc, err := document.Parse(encoded, format)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = doc.Open(testkeys.NewIdentity(1)); err != nil {
+		return nil, err
+	}
+
+	return doc.Encode()
+}
+
+func expectRoundTrip(t *testing.T, contents string, format string) []byte {
+	sealed := sealForTest(t, contents, format)
+
+	opened, err := openForTest(sealed, format)
+	if err != nil {
+		t.Log("Open failed", err)
+		t.FailNow()
+	}
+
+	if string(opened) != contents {
+		t.Log(fmt.Sprintf("Expected \n[%s]\n received \n[%s]", contents, string(opened)))
+		t.Fail()
+	}
+
+	return sealed
+}
+
+func Test_YAMLRoundTripsWithReadableKeys(t *testing.T) {
+	sealed := expectRoundTrip(t, yamlFixture, document.FormatYAML)
+
+	if bytes.Contains(sealed, []byte("hunter2")) || bytes.Contains(sealed, []byte("db.internal")) {
+		t.Log("Expected values to be encrypted")
+		t.Fail()
+	}
+
+	for _, key := range []string{"database:", "password:", "# service configuration", "encrypto:"} {
+		if !bytes.Contains(sealed, []byte(key)) {
+			t.Log(fmt.Sprintf("Expected [%s] to stay readable", key))
+			t.Fail()
+		}
+	}
+}
+
+func Test_JSONRoundTripsWithTypes(t *testing.T) {
+	sealed := expectRoundTrip(t, jsonFixture, document.FormatJSON)
+
+	if !strings.Contains(string(sealed), `"replicas": "ENC[`) {
+		t.Log("Expected every value, including numbers, to be encrypted")
+		t.Fail()
+	}
+}
+
+func Test_TamperingIsDetected(t *testing.T) {
+	sealed := string(sealForTest(t, jsonFixture, document.FormatJSON))
+
+	lines := strings.Split(sealed, "\n")
+	var replicas, ratio string
+	for _, line := range lines {
+		if strings.Contains(line, `"replicas"`) {
+			replicas = line
+		}
+		if strings.Contains(line, `"ratio"`) {
+			ratio = line
+		}
+	}
+
+	swapped := strings.Replace(sealed, replicas, strings.Replace(ratio, `"ratio"`, `"replicas"`, 1), 1)
+	dropped := strings.Replace(sealed, replicas+"\n", "", 1)
+	added := strings.Replace(sealed, `"name"`, `"extra": "plain",
+  "name"`, 1)
+
+	for reason, tampered := range map[string]string{"a value is moved": swapped, "a value is dropped": dropped, "a value is added": added} {
+		if _, err := openForTest([]byte(tampered), document.FormatJSON); err == nil {
+			t.Log("Expected opening to fail when " + reason)
+			t.Fail()
+		}
+	}
+}
+
+func Test_WrongIdentityFails(t *testing.T) {
+	sealed := sealForTest(t, yamlFixture, document.FormatYAML)
+
+	doc, err := document.Parse(sealed, document.FormatYAML)
+	if err != nil {
+		t.Log("Parse failed", err)
+		t.FailNow()
+	}
+
+	if _, err = doc.Open(testkeys.NewIdentity(2)); err == nil {
+		t.Log("Expected the wrong identity to fail")
+		t.Fail()
+	}
+}
+
+func Test_ResealKeepsRecipients(t *testing.T) {
+	sealed := sealForTest(t, yamlFixture, document.FormatYAML)
+
+	doc, _ := document.Parse(sealed, document.FormatYAML)
+	keys, err := doc.Open(testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log("Open failed", err)
+		t.FailNow()
+	}
+
+	if err = doc.Seal(keys); err != nil {
+		t.Log("Seal failed", err)
+		t.FailNow()
+	}
+
+	resealed, _ := doc.Encode()
+	if _, err = openForTest(resealed, document.FormatYAML); err != nil {
+		t.Log("Open of the resealed document failed", err)
+		t.Fail()
+	}
+}
+
+func Test_RejectsUnsupportedDocuments(t *testing.T) {
+	for _, contents := range []string{"- a\n- b\n", "base: &anchor 1\ncopy: *anchor\n"} {
+		if _, err := document.Parse([]byte(contents), document.FormatYAML); err == nil {
+			t.Log(fmt.Sprintf("Expected [%s] to be rejected", contents))
+			t.Fail()
+		}
+	}
+}
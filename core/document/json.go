@@ -0,0 +1,120 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func jsonString(buffer *bytes.Buffer, value string) error {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+
+	// Encode always ends with a newline.
+	buffer.Truncate(buffer.Len() - 1)
+	return nil
+}
+
+func jsonScalar(buffer *bytes.Buffer, node *yaml.Node) error {
+	switch node.ShortTag() {
+	case "!!null":
+		buffer.WriteString("null")
+		return nil
+	case "!!int", "!!float":
+		// Numbers that came from JSON are copied exactly.
+		if json.Valid([]byte(node.Value)) {
+			buffer.WriteString(node.Value)
+			return nil
+		}
+	case "!!bool":
+	default:
+		return jsonString(buffer, node.Value)
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("line [%d]: %w", node.Line, err)
+	}
+
+	buffer.Write(encoded)
+	return nil
+}
+
+func writeJSON(buffer *bytes.Buffer, node *yaml.Node, depth int) error {
+	indent := strings.Repeat("  ", depth+1)
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return jsonScalar(buffer, node)
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("{}")
+			return nil
+		}
+
+		buffer.WriteString("{\n")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			buffer.WriteString(indent)
+			if err := jsonString(buffer, node.Content[i].Value); err != nil {
+				return err
+			}
+			buffer.WriteString(": ")
+
+			if err := writeJSON(buffer, node.Content[i+1], depth+1); err != nil {
+				return err
+			}
+
+			if i+2 < len(node.Content) {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(indent[2:] + "}")
+	case yaml.SequenceNode:
+		if len(node.Content) == 0 {
+			buffer.WriteString("[]")
+			return nil
+		}
+
+		buffer.WriteString("[\n")
+		for i, child := range node.Content {
+			buffer.WriteString(indent)
+			if err := writeJSON(buffer, child, depth+1); err != nil {
+				return err
+			}
+
+			if i+1 < len(node.Content) {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(indent[2:] + "]")
+	default:
+		return fmt.Errorf("line [%d]: unsupported node in a JSON document", node.Line)
+	}
+
+	return nil
+}
+
+// encodeJSON writes the mapping back out in its original key order, which
+// encoding/json would not keep.
+func encodeJSON(node *yaml.Node) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := writeJSON(buffer, node, 0); err != nil {
+		return nil, err
+	}
+
+	buffer.WriteString("\n")
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,365 @@
+package document
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+	"gopkg.in/yaml.v3"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+const (
+	version         = 1
+	encryptedPrefix = "ENC["
+)
+
+// Keys holds the file key and the stanzas that wrap it, so an opened
+// document can be sealed again for the same recipients.
+type Keys struct {
+	cipher  string
+	fileKey []byte
+	stanzas []fileformat.Stanza
+}
+
+func NewKeys(cipherName string, recipients ...fileformat.Recipient) (*Keys, error) {
+	if cipherName == "" {
+		cipherName = fileformat.CipherAESGCM
+	}
+
+	if err := fileformat.ValidateCipher(cipherName); err != nil {
+		return nil, err
+	}
+
+	fileKey, stanzas, err := fileformat.WrapFileKey(recipients...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keys{cipher: cipherName, fileKey: fileKey, stanzas: stanzas}, nil
+}
+
+type stanza struct {
+	Type   string `yaml:"type"`
+	Params string `yaml:"params,omitempty"`
+	Body   string `yaml:"body"`
+}
+
+type metadata struct {
+	Version int      `yaml:"version"`
+	Cipher  string   `yaml:"cipher"`
+	Stanzas []stanza `yaml:"stanzas"`
+	MAC     string   `yaml:"mac"`
+}
+
+type leaf struct {
+	path string
+	node *yaml.Node
+}
+
+func escapePathElement(element string) string {
+	return strings.Replace(strings.Replace(element, "~", "~0", -1), "/", "~1", -1)
+}
+
+// collectLeaves lists every scalar with a JSON pointer style path, skipping the
+// metadata at the top level.
+func collectLeaves(node *yaml.Node, path string, leaves *[]leaf) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*leaves = append(*leaves, leaf{path: path, node: node})
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line [%d]: only scalar keys are supported", key.Line)
+			}
+
+			if path == "" && key.Value == MetadataKey {
+				continue
+			}
+
+			if err := collectLeaves(node.Content[i+1], path+"/"+escapePathElement(key.Value), leaves); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := collectLeaves(child, fmt.Sprintf("%s/%d", path, i), leaves); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("line [%d]: unsupported node", node.Line)
+	}
+
+	return nil
+}
+
+func (document *document) leaves() ([]leaf, error) {
+	leaves := []leaf{}
+	if err := collectLeaves(document.mapping(), "", &leaves); err != nil {
+		return nil, err
+	}
+
+	return leaves, nil
+}
+
+func deriveKey(fileKey []byte, info string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, fileKey, nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func writeField(mac hash.Hash, field string) {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(field)))
+	mac.Write(length)
+	mac.Write([]byte(field))
+}
+
+// documentMAC covers the metadata and every encrypted value with its path,
+// so values can not be changed, moved, added or dropped. Comments and
+// formatting are not covered.
+func documentMAC(fileKey []byte, meta metadata, leaves []leaf) (string, error) {
+	key, err := deriveKey(fileKey, "encrypto document mac")
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	writeField(mac, fmt.Sprintf("encrypto document v%d", meta.Version))
+	writeField(mac, meta.Cipher)
+	for _, stanza := range meta.Stanzas {
+		writeField(mac, stanza.Type)
+		writeField(mac, stanza.Params)
+		writeField(mac, stanza.Body)
+	}
+
+	for _, leaf := range leaves {
+		writeField(mac, leaf.path)
+		writeField(mac, leaf.node.Value)
+	}
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func valueType(node *yaml.Node) string {
+	return strings.TrimPrefix(node.ShortTag(), "!!")
+}
+
+func valueTag(valueType string) string {
+	if strings.HasPrefix(valueType, "!") {
+		return valueType
+	}
+
+	return "!!" + valueType
+}
+
+// The path and type are the associated data, so a value can not be moved
+// to another key or have its type changed.
+func valueAAD(path string, valueType string) []byte {
+	return []byte(path + "\n" + valueType)
+}
+
+func encodeValue(data []byte, valueType string) string {
+	return fmt.Sprintf("%sdata:%s,type:%s]", encryptedPrefix, base64.StdEncoding.EncodeToString(data), valueType)
+}
+
+func decodeValue(value string) ([]byte, string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) || !strings.HasSuffix(value, "]") {
+		return nil, "", errors.New("value is not encrypted")
+	}
+
+	fields := strings.Split(value[len(encryptedPrefix):len(value)-1], ",")
+	if len(fields) != 2 || !strings.HasPrefix(fields[0], "data:") || !strings.HasPrefix(fields[1], "type:") {
+		return nil, "", errors.New("malformed encrypted value")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(fields[0], "data:"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, strings.TrimPrefix(fields[1], "type:"), nil
+}
+
+func (document *document) removeMetadata() {
+	if index := document.metadataIndex(); index >= 0 {
+		mapping := document.mapping()
+		mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	}
+}
+
+func (document *document) Seal(keys *Keys) error {
+	if document.Encrypted() {
+		return errors.New("the document is already encrypted")
+	}
+
+	leaves, err := document.leaves()
+	if err != nil {
+		return err
+	}
+
+	valueKey, err := deriveKey(keys.fileKey, "encrypto document values")
+	if err != nil {
+		return err
+	}
+
+	aead, err := fileformat.NewAEAD(keys.cipher, valueKey)
+	if err != nil {
+		return err
+	}
+
+	encrypted := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return err
+		}
+
+		valueType := valueType(leaf.node)
+		sealed := aead.Seal(nonce, nonce, []byte(leaf.node.Value), valueAAD(leaf.path, valueType))
+		encrypted[i] = encodeValue(sealed, valueType)
+	}
+
+	for i, leaf := range leaves {
+		leaf.node.Value = encrypted[i]
+		leaf.node.Tag = "!!str"
+		leaf.node.Style = 0
+	}
+
+	meta := metadata{Version: version, Cipher: keys.cipher}
+	for _, wrapped := range keys.stanzas {
+		meta.Stanzas = append(meta.Stanzas, stanza{
+			Type:   wrapped.Type,
+			Params: string(wrapped.Params),
+			Body:   base64.StdEncoding.EncodeToString(wrapped.Body),
+		})
+	}
+
+	if meta.MAC, err = documentMAC(keys.fileKey, meta, leaves); err != nil {
+		return err
+	}
+
+	metadataNode := &yaml.Node{}
+	if err = metadataNode.Encode(meta); err != nil {
+		return err
+	}
+
+	mapping := document.mapping()
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: MetadataKey}, metadataNode)
+
+	return nil
+}
+
+func (document *document) Open(identities ...fileformat.Identity) (*Keys, error) {
+	index := document.metadataIndex()
+	if index < 0 {
+		return nil, errors.New("the document is not encrypted")
+	}
+
+	var meta metadata
+	if err := document.mapping().Content[index+1].Decode(&meta); err != nil {
+		return nil, fmt.Errorf("could not read the [%s] metadata: %w", MetadataKey, err)
+	}
+
+	if meta.Version != version {
+		return nil, fmt.Errorf("unsupported document version [%d]", meta.Version)
+	}
+
+	stanzas := []fileformat.Stanza{}
+	for _, encoded := range meta.Stanzas {
+		body, err := base64.StdEncoding.DecodeString(encoded.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		stanza := fileformat.Stanza{Type: encoded.Type, Body: body}
+		if encoded.Params != "" {
+			stanza.Params = json.RawMessage(encoded.Params)
+		}
+
+		stanzas = append(stanzas, stanza)
+	}
+
+	leaves, err := document.leaves()
+	if err != nil {
+		return nil, err
+	}
+
+	verify := func(fileKey []byte) error {
+		expected, err := documentMAC(fileKey, meta, leaves)
+		if err != nil {
+			return err
+		}
+
+		if !hmac.Equal([]byte(expected), []byte(meta.MAC)) {
+			return errors.New("document authentication failed")
+		}
+
+		return nil
+	}
+
+	fileKey, err := fileformat.UnwrapFileKey(stanzas, identities, verify)
+	if err != nil {
+		return nil, err
+	}
+
+	valueKey, err := deriveKey(fileKey, "encrypto document values")
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := fileformat.NewAEAD(meta.Cipher, valueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintexts := make([]string, len(leaves))
+	types := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		data, valueType, err := decodeValue(leaf.node.Value)
+		if err != nil {
+			return nil, fmt.Errorf("[%s]: %w", leaf.path, err)
+		}
+
+		if len(data) < aead.NonceSize() {
+			return nil, fmt.Errorf("[%s]: encrypted value is too short", leaf.path)
+		}
+
+		plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], valueAAD(leaf.path, valueType))
+		if err != nil {
+			return nil, fmt.Errorf("[%s]: value failed authentication", leaf.path)
+		}
+
+		plaintexts[i] = string(plaintext)
+		types[i] = valueType
+	}
+
+	for i, leaf := range leaves {
+		leaf.node.Value = plaintexts[i]
+		leaf.node.Tag = valueTag(types[i])
+		leaf.node.Style = 0
+		if types[i] == "str" && strings.Contains(plaintexts[i], "\n") {
+			leaf.node.Style = yaml.LiteralStyle
+		}
+	}
+
+	document.removeMetadata()
+
+	return &Keys{cipher: meta.Cipher, fileKey: fileKey, stanzas: stanzas}, nil
+}
@@ -0,0 +1,201 @@
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Variable struct {
+	Name  string
+	Value string
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		digit := r >= '0' && r <= '9'
+		if !letter && !(digit && i > 0) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var escapes = map[byte]byte{'n': '\n', 'r': '\r', 't': '\t', '"': '"', '\\': '\\', '$': '$'}
+
+type parser struct {
+	lines  []string
+	number int
+}
+
+func (parser *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line [%d]: %s", parser.number, fmt.Sprintf(format, args...))
+}
+
+// doubleQuoted reads a value that may span several lines up to the closing
+// quote, returning whatever follows the quote.
+func (parser *parser) doubleQuoted(rest string) (string, string, error) {
+	value := strings.Builder{}
+
+	for {
+		for i := 0; i < len(rest); i++ {
+			switch rest[i] {
+			case '"':
+				return value.String(), rest[i+1:], nil
+			case '\\':
+				if i+1 == len(rest) {
+					return "", "", parser.errorf("unfinished escape")
+				}
+
+				escaped, ok := escapes[rest[i+1]]
+				if !ok {
+					return "", "", parser.errorf("unknown escape [\\%c]", rest[i+1])
+				}
+
+				value.WriteByte(escaped)
+				i++
+			default:
+				value.WriteByte(rest[i])
+			}
+		}
+
+		if len(parser.lines) == 0 {
+			return "", "", parser.errorf("unterminated double quoted value")
+		}
+
+		value.WriteByte('\n')
+		rest = parser.lines[0]
+		parser.lines = parser.lines[1:]
+		parser.number++
+	}
+}
+
+func (parser *parser) value(raw string) (string, error) {
+	raw = strings.TrimLeft(raw, " \t")
+
+	var value, rest string
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", parser.errorf("unterminated single quoted value")
+		}
+
+		value, rest = raw[1:end+1], raw[end+2:]
+	case strings.HasPrefix(raw, `"`):
+		var err error
+		if value, rest, err = parser.doubleQuoted(raw[1:]); err != nil {
+			return "", err
+		}
+	default:
+		if comment := strings.Index(raw, " #"); comment >= 0 {
+			raw = raw[:comment]
+		}
+		if comment := strings.Index(raw, "\t#"); comment >= 0 {
+			raw = raw[:comment]
+		}
+
+		return strings.TrimSpace(raw), nil
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", parser.errorf("unexpected text after a quoted value")
+	}
+
+	return value, nil
+}
+
+// Parse reads KEY=value lines. Blank lines and # comments are skipped, an
+// optional export prefix is allowed, single quoted values are literal and
+// double quoted values support escapes and may span lines. Later
+// definitions of a name replace earlier ones.
+func Parse(src io.Reader) ([]Variable, error) {
+	lines := []string{}
+
+	scanner := bufio.NewScanner(src)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	parser := &parser{lines: lines}
+	variables := []Variable{}
+	positions := map[string]int{}
+
+	for len(parser.lines) > 0 {
+		line := strings.TrimSpace(parser.lines[0])
+		parser.lines = parser.lines[1:]
+		parser.number++
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+
+		separator := strings.Index(line, "=")
+		if separator < 0 {
+			return nil, parser.errorf("expected NAME=value")
+		}
+
+		name := strings.TrimSpace(line[:separator])
+		if !validName(name) {
+			return nil, parser.errorf("invalid variable name [%s]", name)
+		}
+
+		value, err := parser.value(line[separator+1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if position, ok := positions[name]; ok {
+			variables[position].Value = value
+			continue
+		}
+
+		positions[name] = len(variables)
+		variables = append(variables, Variable{Name: name, Value: value})
+	}
+
+	return variables, nil
+}
+
+// Environ overlays variables on an os.Environ style list, replacing any
+// entries with the same name.
+func Environ(base []string, variables []Variable) []string {
+	replaced := map[string]bool{}
+	for _, variable := range variables {
+		replaced[variable.Name] = true
+	}
+
+	environ := []string{}
+	for _, entry := range base {
+		name := entry
+		if separator := strings.Index(entry, "="); separator >= 0 {
+			name = entry[:separator]
+		}
+
+		if !replaced[name] {
+			environ = append(environ, entry)
+		}
+	}
+
+	for _, variable := range variables {
+		environ = append(environ, variable.Name+"="+variable.Value)
+	}
+
+	return environ
+}
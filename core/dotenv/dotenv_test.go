@@ -0,0 +1,109 @@
+package dotenv_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/dotenv"
+)
+
+func parseForTest(t *testing.T, contents string) map[string]string {
+	variables, err := dotenv.Parse(strings.NewReader(contents))
+	if err != nil {
+		t.Log("Parse failed", err)
+		t.FailNow()
+	}
+
+	parsed := map[string]string{}
+	for _, variable := range variables {
+		parsed[variable.Name] = variable.Value
+	}
+
+	return parsed
+}
+
+func expectValue(t *testing.T, parsed map[string]string, name string, expected string) {
+	if parsed[name] != expected {
+		t.Log(fmt.Sprintf("Expected [%s] to be [%s] but was [%s]", name, expected, parsed[name]))
+		t.Fail()
+	}
+}
+
+func Test_ParsesCommonForms(t *testing.T) {
+	parsed := parseForTest(t, strings.Join([]string{
+		"# database",
+		"",
+		"DB_HOST=localhost",
+		"export DB_PORT = 5432",
+		"PLAIN=some value # trailing comment",
+		"HASH=abc#def",
+		"SINGLE='literal $HOME \\n'",
+		`DOUBLE="line one\nline \"two\""`,
+		"EMPTY=",
+		"WINDOWS=crlf\r",
+	}, "\n"))
+
+	expectValue(t, parsed, "DB_HOST", "localhost")
+	expectValue(t, parsed, "DB_PORT", "5432")
+	expectValue(t, parsed, "PLAIN", "some value")
+	expectValue(t, parsed, "HASH", "abc#def")
+	expectValue(t, parsed, "SINGLE", "literal $HOME \\n")
+	expectValue(t, parsed, "DOUBLE", "line one\nline \"two\"")
+	expectValue(t, parsed, "EMPTY", "")
+	expectValue(t, parsed, "WINDOWS", "crlf")
+}
+
+func Test_DoubleQuotedValuesMaySpanLines(t *testing.T) {
+	parsed := parseForTest(t, "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nAFTER=1\n")
+
+	expectValue(t, parsed, "KEY", "-----BEGIN-----\nabc\n-----END-----")
+	expectValue(t, parsed, "AFTER", "1")
+}
+
+func Test_LaterDefinitionsWin(t *testing.T) {
+	variables, err := dotenv.Parse(strings.NewReader("A=1\nB=2\nA=3\n"))
+	if err != nil {
+		t.Log("Parse failed", err)
+		t.FailNow()
+	}
+
+	if len(variables) != 2 || variables[0].Name != "A" || variables[0].Value != "3" {
+		t.Log(fmt.Sprintf("Unexpected variables %+v", variables))
+		t.Fail()
+	}
+}
+
+func Test_RejectsMalformedLines(t *testing.T) {
+	for _, contents := range []string{
+		"NO_EQUALS",
+		"1BAD=x",
+		"BAD-NAME=x",
+		"OPEN='never closed",
+		"OPEN=\"never closed\nstill open",
+		`ESCAPE="\q"`,
+		"TRAILING='quoted' extra",
+	} {
+		if _, err := dotenv.Parse(strings.NewReader(contents)); err == nil {
+			t.Log(fmt.Sprintf("Expected [%s] to be rejected", contents))
+			t.Fail()
+		}
+	}
+}
+
+func Test_ErrorsNameTheLine(t *testing.T) {
+	_, err := dotenv.Parse(strings.NewReader("A=1\n\nBROKEN\n"))
+	if err == nil || !strings.Contains(err.Error(), "line [3]") {
+		t.Log("Expected the error to name line 3", err)
+		t.Fail()
+	}
+}
+
+func Test_EnvironReplacesExistingNames(t *testing.T) {
+	environ := dotenv.Environ([]string{"PATH=/bin", "TOKEN=old"}, []dotenv.Variable{{Name: "TOKEN", Value: "new"}})
+
+	if strings.Join(environ, " ") != "PATH=/bin TOKEN=new" {
+		t.Log(fmt.Sprintf("Unexpected environment %v", environ))
+		t.Fail()
+	}
+}
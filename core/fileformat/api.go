@@ -0,0 +1,67 @@
+package fileformat
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	CurrentVersion          = 3
+	CipherAESGCM            = "aes-256-gcm"
+	CipherChaCha20Poly1305  = "chacha20-poly1305"
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+	FileKeySize             = 32
+)
+
+var Ciphers = []string{CipherAESGCM, CipherChaCha20Poly1305, CipherXChaCha20Poly1305}
+
+var ErrIncorrectIdentity = errors.New("identity does not match stanza")
+
+type Stanza struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Body   []byte          `json:"body"`
+}
+
+type Header struct {
+	Version   int      `json:"version"`
+	Cipher    string   `json:"cipher"`
+	ChunkSize int      `json:"chunkSize,omitempty"`
+	Nonce     []byte   `json:"nonce"`
+	Stanzas   []Stanza `json:"stanzas"`
+	// Metadata is kept as the exact bytes that were written, because those
+	// bytes are also the associated data of every payload chunk.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+type Metadata struct {
+	Name    string      `json:"name"`
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+}
+
+type Options struct {
+	Metadata *Metadata
+	// Cipher is one of Ciphers and defaults to CipherAESGCM.
+	Cipher string
+}
+
+type Reader interface {
+	io.Reader
+	Header() Header
+	Metadata() *Metadata
+	// Reseal writes a new payload for the same recipients and cipher.
+	Reseal(dst io.Writer, metadata *Metadata) (io.WriteCloser, error)
+}
+
+type Recipient interface {
+	Wrap(fileKey []byte) (Stanza, error)
+}
+
+type Identity interface {
+	Unwrap(stanza Stanza) ([]byte, error)
+}
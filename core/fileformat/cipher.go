@@ -0,0 +1,78 @@
+package fileformat
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// prefixedAEAD fills the leading bytes of a long nonce with a fixed per file
+// prefix, so the stream code only ever deals with the counter and flag bytes.
+type prefixedAEAD struct {
+	cipher.AEAD
+	prefix []byte
+}
+
+func (aead *prefixedAEAD) NonceSize() int {
+	return aead.AEAD.NonceSize() - len(aead.prefix)
+}
+
+func (aead *prefixedAEAD) fullNonce(nonce []byte) []byte {
+	full := make([]byte, 0, aead.AEAD.NonceSize())
+	full = append(full, aead.prefix...)
+
+	return append(full, nonce...)
+}
+
+func (aead *prefixedAEAD) Seal(dst []byte, nonce []byte, plaintext []byte, additionalData []byte) []byte {
+	return aead.AEAD.Seal(dst, aead.fullNonce(nonce), plaintext, additionalData)
+}
+
+func (aead *prefixedAEAD) Open(dst []byte, nonce []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	return aead.AEAD.Open(dst, aead.fullNonce(nonce), ciphertext, additionalData)
+}
+
+func ValidateCipher(name string) error {
+	for _, supported := range Ciphers {
+		if name == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported cipher [%s]", name)
+}
+
+func NewAEAD(name string, key []byte) (cipher.AEAD, error) {
+	switch name {
+	case CipherAESGCM:
+		return newGCM(key)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unsupported cipher [%s]", name)
+	}
+}
+
+// newPayloadCipher builds the AEAD for a payload. XChaCha20-Poly1305 nonces
+// start with the random header nonce, the other ciphers use the counter and
+// flag alone since every payload key is already unique to its file.
+func newPayloadCipher(name string, key []byte, salt []byte) (cipher.AEAD, error) {
+	aead, err := NewAEAD(name, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if name != CipherXChaCha20Poly1305 {
+		return aead, nil
+	}
+
+	prefixSize := chacha20poly1305.NonceSizeX - chacha20poly1305.NonceSize
+	if len(salt) < prefixSize {
+		return nil, fmt.Errorf("header nonce must be at least [%d] bytes for [%s]", prefixSize, name)
+	}
+
+	return &prefixedAEAD{AEAD: aead, prefix: salt[:prefixSize]}, nil
+}
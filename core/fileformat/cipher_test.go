@@ -0,0 +1,104 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+func encryptWithCipher(t *testing.T, plaintext []byte, cipherName string) []byte {
+	encrypted := bytes.NewBuffer(nil)
+
+	writer, err := fileformat.NewWriterWithOptions(encrypted, fileformat.Options{Cipher: cipherName}, testkeys.NewRecipient(1))
+	if err != nil {
+		t.Log(fmt.Sprintf("NewWriterWithOptions for [%s] failed", cipherName), err)
+		t.FailNow()
+	}
+
+	writer.Write(plaintext)
+	if err = writer.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	return encrypted.Bytes()
+}
+
+func Test_EveryCipherRoundTrips(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("cipher agility "), fileformat.DefaultChunkSize/5)
+
+	for _, cipherName := range fileformat.Ciphers {
+		encrypted := encryptWithCipher(t, plaintext, cipherName)
+
+		reader, err := fileformat.NewReader(bytes.NewReader(encrypted), testkeys.NewIdentity(1))
+		if err != nil {
+			t.Log(fmt.Sprintf("NewReader for [%s] failed", cipherName), err)
+			t.FailNow()
+		}
+
+		if reader.Header().Cipher != cipherName {
+			t.Log(fmt.Sprintf("Expected the header to record [%s] but found [%s]", cipherName, reader.Header().Cipher))
+			t.Fail()
+		}
+
+		decrypted := bytes.NewBuffer(nil)
+		if _, err = io.Copy(decrypted, reader); err != nil {
+			t.Log(fmt.Sprintf("Decrypt for [%s] failed", cipherName), err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Log(fmt.Sprintf("Expected [%s] to round trip", cipherName))
+			t.Fail()
+		}
+	}
+}
+
+func Test_EveryCipherSupportsRandomAccess(t *testing.T) {
+	plaintext := bytes.Repeat([]byte{7, 8, 9}, fileformat.DefaultChunkSize)
+
+	for _, cipherName := range fileformat.Ciphers {
+		encrypted := encryptWithCipher(t, plaintext, cipherName)
+
+		reader, err := fileformat.NewReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), testkeys.NewIdentity(1))
+		if err != nil {
+			t.Log(fmt.Sprintf("NewReaderAt for [%s] failed", cipherName), err)
+			t.FailNow()
+		}
+
+		offset := 2*fileformat.DefaultChunkSize - 3
+		buffer := make([]byte, 10)
+		if _, err = reader.ReadAt(buffer, int64(offset)); err != nil {
+			t.Log(fmt.Sprintf("ReadAt for [%s] failed", cipherName), err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(buffer, plaintext[offset:offset+10]) {
+			t.Log(fmt.Sprintf("Unexpected bytes from [%s]", cipherName))
+			t.Fail()
+		}
+	}
+}
+
+func Test_SwappedCipherFails(t *testing.T) {
+	encrypted := encryptWithCipher(t, []byte("hello"), fileformat.CipherChaCha20Poly1305)
+
+	swapped := bytes.Replace(encrypted, []byte(`"chacha20-poly1305"`), []byte(`"aes-256-gcm"      `), 1)
+
+	if _, err := decryptForTest(swapped, testkeys.NewIdentity(1)); err == nil {
+		t.Log("Expected a header naming a different cipher to fail")
+		t.Fail()
+	}
+}
+
+func Test_UnknownCipherIsRejected(t *testing.T) {
+	_, err := fileformat.NewWriterWithOptions(bytes.NewBuffer(nil), fileformat.Options{Cipher: "rot13"}, testkeys.NewRecipient(1))
+	if err == nil {
+		t.Log("Expected an unknown cipher to be rejected")
+		t.Fail()
+	}
+}
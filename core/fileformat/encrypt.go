@@ -0,0 +1,208 @@
+package fileformat
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+func newPayloadKey(fileKey []byte, salt []byte) ([]byte, error) {
+	return deriveKey(fileKey, salt, "encrypto payload")
+}
+
+func newPayloadAEAD(header Header, fileKey []byte) (cipher.AEAD, error) {
+	if err := validateChunkSize(header.ChunkSize); err != nil {
+		return nil, err
+	}
+
+	payloadKey, err := newPayloadKey(fileKey, header.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPayloadCipher(header.Cipher, payloadKey, header.Nonce)
+}
+
+func NewWriter(dst io.Writer, recipients ...Recipient) (io.WriteCloser, error) {
+	return NewWriterWithOptions(dst, Options{}, recipients...)
+}
+
+func newHeader(options Options) (Header, error) {
+	cipherName := options.Cipher
+	if cipherName == "" {
+		cipherName = CipherAESGCM
+	}
+
+	if err := ValidateCipher(cipherName); err != nil {
+		return Header{}, err
+	}
+
+	header := Header{
+		Version:   CurrentVersion,
+		Cipher:    cipherName,
+		ChunkSize: DefaultChunkSize,
+		Nonce:     make([]byte, 16),
+	}
+
+	if _, err := io.ReadFull(rand.Reader, header.Nonce); err != nil {
+		return Header{}, err
+	}
+
+	if options.Metadata != nil {
+		metadata, err := json.Marshal(options.Metadata)
+		if err != nil {
+			return Header{}, err
+		}
+
+		header.Metadata = metadata
+	}
+
+	return header, nil
+}
+
+func sealPayload(dst io.Writer, header Header, fileKey []byte) (io.WriteCloser, error) {
+	aead, err := newPayloadAEAD(header, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeHeader(dst, header, fileKey); err != nil {
+		return nil, err
+	}
+
+	return newStreamWriter(aead, dst, header.ChunkSize, header.Metadata), nil
+}
+
+func NewWriterWithOptions(dst io.Writer, options Options, recipients ...Recipient) (io.WriteCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+
+	header, err := newHeader(options)
+	if err != nil {
+		return nil, err
+	}
+
+	fileKey, stanzas, err := WrapFileKey(recipients...)
+	if err != nil {
+		return nil, err
+	}
+	header.Stanzas = stanzas
+
+	return sealPayload(dst, header, fileKey)
+}
+
+type reader struct {
+	io.Reader
+	header   Header
+	metadata *Metadata
+	fileKey  []byte
+}
+
+// Reseal keeps the stanzas and the file key, so every recipient can still
+// decrypt, while the fresh header nonce gives the new payload its own key.
+func (reader *reader) Reseal(dst io.Writer, metadata *Metadata) (io.WriteCloser, error) {
+	header, err := newHeader(Options{Metadata: metadata, Cipher: reader.header.Cipher})
+	if err != nil {
+		return nil, err
+	}
+
+	header.Stanzas = reader.header.Stanzas
+
+	return sealPayload(dst, header, reader.fileKey)
+}
+
+func (reader *reader) Header() Header {
+	return reader.header
+}
+
+func (reader *reader) Metadata() *Metadata {
+	return reader.metadata
+}
+
+func parseMetadata(header Header) (*Metadata, error) {
+	if len(header.Metadata) == 0 {
+		return nil, nil
+	}
+
+	metadata := &Metadata{}
+	if err := json.Unmarshal(header.Metadata, metadata); err != nil {
+		return nil, fmt.Errorf("could not parse metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+// Version 1 files hold a single GCM message after the header and are only
+// ever read back whole.
+func readVersionOne(src io.Reader, header Header, fileKey []byte) (io.Reader, error) {
+	payloadKey, err := newPayloadKey(fileKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(payloadKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := ioutil.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, header.Nonce, ciphertext, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(plaintext), nil
+}
+
+func NewReader(src io.Reader, identities ...Identity) (Reader, error) {
+	parsed, err := readHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	header := parsed.header
+	if header.Version == 1 && header.Cipher != CipherAESGCM {
+		return nil, fmt.Errorf("unsupported cipher [%s]", header.Cipher)
+	}
+
+	metadata, err := parseMetadata(header)
+	if err != nil {
+		return nil, err
+	}
+
+	fileKey, err := parsed.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.Version == 1 {
+		plaintext, err := readVersionOne(src, header, fileKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &reader{Reader: plaintext, header: header, fileKey: fileKey}, nil
+	}
+
+	aead, err := newPayloadAEAD(header, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		Reader:   newStreamReader(aead, src, header.ChunkSize, header.Metadata),
+		header:   header,
+		metadata: metadata,
+		fileKey:  fileKey,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+const versionOneFixture = "RU5DUllQVE8AAACleyJ2ZXJzaW9uIjoxLCJjaXBoZXIiOiJhZXMtMjU2LWdjbSIsIm5vbmNlIjoiYU9pT2NhOWdyMEZsb055SiIsInN0YW56YXMiOlt7InR5cGUiOiJ0ZXN0IiwiYm9keSI6IkwxemV1QUhkMC9IWktJcGJLZ05iZ0RyNEltcXRNTU90T1RMek11d0hYdVk0bXZwbXcvQmdMK2lMM1hmM25Bc2EifV19eIdjw/2ubyG2cbGTGBuiijjs42nJMLeW+3hMonLy/lBKc38tZHZYhYghPZftWaWjCTTLUSaL95VcFs8Xh5bMFPwlS+GSKw=="
+
+func encryptForTest(t *testing.T, plaintext []byte, recipients ...fileformat.Recipient) []byte {
+	encrypted := bytes.NewBuffer(nil)
+
+	writer, err := fileformat.NewWriter(encrypted, recipients...)
+	if err != nil {
+		t.Log("NewWriter failed", err)
+		t.FailNow()
+	}
+
+	if _, err = writer.Write(plaintext); err != nil {
+		t.Log("Write failed", err)
+		t.FailNow()
+	}
+
+	if err = writer.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	return encrypted.Bytes()
+}
+
+func decryptForTest(encrypted []byte, identities ...fileformat.Identity) ([]byte, error) {
+	reader, err := fileformat.NewReader(bytes.NewReader(encrypted), identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(reader)
+}
+
+func expectRoundTrip(t *testing.T, plaintext []byte) {
+	encrypted := encryptForTest(t, plaintext, testkeys.NewRecipient(1))
+
+	decrypted, err := decryptForTest(encrypted, testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log(fmt.Sprintf("Decrypt of [%d] bytes failed", len(plaintext)), err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Log(fmt.Sprintf("Expected the [%d] decrypted bytes to match the plaintext", len(plaintext)))
+		t.Fail()
+	}
+}
+
+func Test_CanRoundTrip(t *testing.T) {
+	expectRoundTrip(t, []byte("a message longer than thirty two characters, which used to be ignored"))
+}
+
+func Test_CanRoundTripAcrossChunkBoundaries(t *testing.T) {
+	chunk := fileformat.DefaultChunkSize
+
+	for _, size := range []int{0, 1, chunk - 1, chunk, chunk + 1, 3 * chunk, 3*chunk + 17} {
+		expectRoundTrip(t, bytes.Repeat([]byte{'x'}, size))
+	}
+}
+
+func Test_AnyRecipientCanDecrypt(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("shared"), testkeys.NewRecipient(1), testkeys.NewRecipient(2))
+
+	for _, fill := range []byte{1, 2} {
+		if _, err := decryptForTest(encrypted, testkeys.NewIdentity(fill)); err != nil {
+			t.Log(fmt.Sprintf("Identity [%d] could not decrypt", fill), err)
+			t.Fail()
+		}
+	}
+}
+
+func Test_WrongIdentityFails(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("secret"), testkeys.NewRecipient(1))
+
+	_, err := decryptForTest(encrypted, testkeys.NewIdentity(2))
+	if err == nil {
+		t.Log("Expected decryption with the wrong identity to fail")
+		t.Fail()
+	}
+}
+
+func Test_TamperedHeaderFails(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("secret"), testkeys.NewRecipient(1))
+	tampered := bytes.Replace(encrypted, []byte(`"cipher"`), []byte(`"Cipher"`), 1)
+
+	_, err := decryptForTest(tampered, testkeys.NewIdentity(1))
+	if err == nil {
+		t.Log("Expected decryption of a modified header to fail")
+		t.Fail()
+	}
+}
+
+func Test_UnknownVersionFails(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("secret"), testkeys.NewRecipient(1))
+	current := fmt.Sprintf(`"version":%d`, fileformat.CurrentVersion)
+	future := bytes.Replace(encrypted, []byte(current), []byte(`"version":9`), 1)
+
+	_, err := decryptForTest(future, testkeys.NewIdentity(1))
+	if err == nil {
+		t.Log("Expected decryption of an unknown version to fail")
+		t.Fail()
+	}
+}
+
+func Test_CanReadVersionOne(t *testing.T) {
+	encrypted, _ := base64.StdEncoding.DecodeString(versionOneFixture)
+
+	decrypted, err := decryptForTest(encrypted, testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log("Decrypt of a version one file failed", err)
+		t.FailNow()
+	}
+
+	if string(decrypted) != "written by version one" {
+		t.Log(fmt.Sprintf("Unexpected plaintext [%s]", string(decrypted)))
+		t.Fail()
+	}
+}
+
+type explainingIdentity struct{}
+
+func (identity *explainingIdentity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	return nil, fmt.Errorf("%w: this identity can not open [%s] stanzas", fileformat.ErrIncorrectIdentity, stanza.Type)
+}
+
+func Test_ExplainedMismatchIsReported(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("explained"), testkeys.NewRecipient(1))
+
+	_, err := decryptForTest(encrypted, &explainingIdentity{}, testkeys.NewIdentity(2))
+	if err == nil || err.Error() != "identity does not match stanza: this identity can not open [test] stanzas" {
+		t.Log("Expected the explanation when no identity matches, received", err)
+		t.Fail()
+	}
+
+	decrypted, err := decryptForTest(encrypted, &explainingIdentity{}, testkeys.NewIdentity(1))
+	if err != nil || string(decrypted) != "explained" {
+		t.Log("Expected a later identity to still decrypt", err)
+		t.Fail()
+	}
+}
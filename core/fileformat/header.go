@@ -0,0 +1,136 @@
+package fileformat
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+var magic = []byte("ENCRYPTO")
+
+const (
+	macSize        = sha256.Size
+	maxHeaderBytes = 1 << 20
+)
+
+func deriveKey(fileKey []byte, salt []byte, info string) ([]byte, error) {
+	key := make([]byte, 32)
+
+	_, err := io.ReadFull(hkdf.New(sha256.New, fileKey, salt, []byte(info)), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func headerMAC(fileKey []byte, encoded []byte) ([]byte, error) {
+	macKey, err := deriveKey(fileKey, nil, "encrypto header")
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(encoded)
+
+	return mac.Sum(nil), nil
+}
+
+func encodeHeader(header Header) ([]byte, error) {
+	headerJson, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	buffer.Write(magic)
+	binary.Write(buffer, binary.BigEndian, uint32(len(headerJson)))
+	buffer.Write(headerJson)
+
+	return buffer.Bytes(), nil
+}
+
+func writeHeader(dst io.Writer, header Header, fileKey []byte) error {
+	encoded, err := encodeHeader(header)
+	if err != nil {
+		return err
+	}
+
+	mac, err := headerMAC(fileKey, encoded)
+	if err != nil {
+		return err
+	}
+
+	if _, err = dst.Write(encoded); err != nil {
+		return err
+	}
+
+	_, err = dst.Write(mac)
+	return err
+}
+
+type parsedHeader struct {
+	header  Header
+	encoded []byte
+	mac     []byte
+}
+
+func (parsed *parsedHeader) verify(fileKey []byte) error {
+	expected, err := headerMAC(fileKey, parsed.encoded)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(expected, parsed.mac) {
+		return errors.New("header authentication failed")
+	}
+
+	return nil
+}
+
+func readHeader(src io.Reader) (*parsedHeader, error) {
+	prefix := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(src, prefix); err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	if !bytes.Equal(prefix[:len(magic)], magic) {
+		return nil, errors.New("input is not an encrypto file")
+	}
+
+	length := binary.BigEndian.Uint32(prefix[len(magic):])
+	if length > maxHeaderBytes {
+		return nil, fmt.Errorf("header length [%d] exceeds the maximum of [%d]", length, maxHeaderBytes)
+	}
+
+	rest := make([]byte, int(length)+macSize)
+	if _, err := io.ReadFull(src, rest); err != nil {
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+
+	var header Header
+	if err := json.Unmarshal(rest[:length], &header); err != nil {
+		return nil, fmt.Errorf("could not parse header: %w", err)
+	}
+
+	if header.Version < 1 || header.Version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported format version [%d]", header.Version)
+	}
+
+	return &parsedHeader{
+		header:  header,
+		encoded: append(prefix, rest[:length]...),
+		mac:     rest[length:],
+	}, nil
+}
+
+func (parsed *parsedHeader) unwrap(identities []Identity) ([]byte, error) {
+	return UnwrapFileKey(parsed.header.Stanzas, identities, parsed.verify)
+}
@@ -0,0 +1,80 @@
+package fileformat
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// tagSize is the authentication tag added by every supported cipher.
+const tagSize = 16
+
+type Description struct {
+	Header        Header
+	HeaderSize    int64
+	PayloadSize   int64
+	Chunks        int64
+	PlaintextSize int64
+}
+
+// chunkLayout works out how a payload of payloadSize bytes splits into
+// sealed chunks and how large the final one is.
+func chunkLayout(chunkSize int, payloadSize int64) (int64, int64, error) {
+	sealedSize := int64(chunkSize) + tagSize
+
+	chunks := (payloadSize + sealedSize - 1) / sealedSize
+	if chunks == 0 {
+		return 0, 0, ErrTruncated
+	}
+
+	lastSealed := payloadSize - (chunks-1)*sealedSize
+	if lastSealed < tagSize || (lastSealed == tagSize && chunks > 1) {
+		return 0, 0, ErrTruncated
+	}
+
+	return chunks, lastSealed, nil
+}
+
+// Inspect reads the header and measures the payload without any key. Nothing
+// it returns is authenticated, that needs the file key.
+func Inspect(src io.Reader) (Description, error) {
+	counter := &countingReader{src: src}
+
+	parsed, err := readHeader(counter)
+	if err != nil {
+		return Description{}, err
+	}
+
+	payloadSize, err := io.Copy(ioutil.Discard, src)
+	if err != nil {
+		return Description{}, err
+	}
+
+	description := Description{
+		Header:      parsed.header,
+		HeaderSize:  counter.read,
+		PayloadSize: payloadSize,
+	}
+
+	if parsed.header.Version == 1 {
+		if payloadSize < tagSize {
+			return Description{}, ErrTruncated
+		}
+
+		description.Chunks = 1
+		description.PlaintextSize = payloadSize - tagSize
+
+		return description, nil
+	}
+
+	if err = validateChunkSize(parsed.header.ChunkSize); err != nil {
+		return Description{}, err
+	}
+
+	description.Chunks, _, err = chunkLayout(parsed.header.ChunkSize, payloadSize)
+	if err != nil {
+		return Description{}, err
+	}
+	description.PlaintextSize = payloadSize - description.Chunks*tagSize
+
+	return description, nil
+}
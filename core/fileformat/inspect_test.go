@@ -0,0 +1,54 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+func Test_InspectCountsChunks(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+
+	description, err := fileformat.Inspect(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Log("Inspect failed", err)
+		t.FailNow()
+	}
+
+	if description.Chunks != 3 || description.PlaintextSize != 2*fileformat.DefaultChunkSize+100 || description.HeaderSize != int64(headerSize) {
+		t.Log(fmt.Sprintf("Unexpected description %+v", description))
+		t.Fail()
+	}
+
+	if len(description.Header.Stanzas) != 1 || description.Header.Cipher != fileformat.CipherAESGCM {
+		t.Log(fmt.Sprintf("Unexpected header %+v", description.Header))
+		t.Fail()
+	}
+}
+
+func Test_InspectReadsVersionOne(t *testing.T) {
+	encrypted, _ := base64.StdEncoding.DecodeString(versionOneFixture)
+
+	description, err := fileformat.Inspect(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Log("Inspect failed", err)
+		t.FailNow()
+	}
+
+	if description.Header.Version != 1 || description.Chunks != 1 || description.PlaintextSize != int64(len("written by version one")) {
+		t.Log(fmt.Sprintf("Unexpected description %+v", description))
+		t.Fail()
+	}
+}
+
+func Test_InspectDetectsTruncation(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+
+	if _, err := fileformat.Inspect(bytes.NewReader(encrypted[:headerSize+5])); err == nil {
+		t.Log("Expected a payload shorter than one tag to be reported as truncated")
+		t.Fail()
+	}
+}
@@ -0,0 +1,86 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+func encryptWithMetadata(t *testing.T, plaintext []byte, metadata *fileformat.Metadata) []byte {
+	encrypted := bytes.NewBuffer(nil)
+
+	writer, err := fileformat.NewWriterWithOptions(encrypted, fileformat.Options{Metadata: metadata}, testkeys.NewRecipient(1))
+	if err != nil {
+		t.Log("NewWriterWithOptions failed", err)
+		t.FailNow()
+	}
+
+	writer.Write(plaintext)
+	if err = writer.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	return encrypted.Bytes()
+}
+
+func newTestMetadata() *fileformat.Metadata {
+	return &fileformat.Metadata{
+		Name:    "runbook.txt",
+		Size:    5,
+		Mode:    0640,
+		ModTime: time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC),
+	}
+}
+
+func Test_MetadataIsRestored(t *testing.T) {
+	encrypted := encryptWithMetadata(t, []byte("hello"), newTestMetadata())
+
+	reader, err := fileformat.NewReader(bytes.NewReader(encrypted), testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log("NewReader failed", err)
+		t.FailNow()
+	}
+
+	metadata := reader.Metadata()
+	if metadata == nil {
+		t.Log("Expected metadata to be present")
+		t.FailNow()
+	}
+
+	expected := newTestMetadata()
+	if metadata.Name != expected.Name || metadata.Size != expected.Size || metadata.Mode != expected.Mode || !metadata.ModTime.Equal(expected.ModTime) {
+		t.Log(fmt.Sprintf("Expected %+v received %+v", expected, metadata))
+		t.Fail()
+	}
+
+	if plaintext, err := ioutil.ReadAll(reader); err != nil || string(plaintext) != "hello" {
+		t.Log("Expected the payload to decrypt", err)
+		t.Fail()
+	}
+}
+
+func Test_RenamedMetadataFails(t *testing.T) {
+	encrypted := encryptWithMetadata(t, []byte("hello"), newTestMetadata())
+	renamed := bytes.Replace(encrypted, []byte("runbook.txt"), []byte("payroll.txt"), 1)
+
+	if _, err := decryptForTest(renamed, testkeys.NewIdentity(1)); err == nil {
+		t.Log("Expected a renamed file to fail authentication")
+		t.Fail()
+	}
+}
+
+func Test_FilesWithoutMetadataStillDecrypt(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("anonymous"), testkeys.NewRecipient(1))
+
+	reader, err := fileformat.NewReader(bytes.NewReader(encrypted), testkeys.NewIdentity(1))
+	if err != nil || reader.Metadata() != nil {
+		t.Log("Expected a file without metadata to open without metadata", err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,155 @@
+package fileformat
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type SizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+type chunkReaderAt struct {
+	aead          cipher.AEAD
+	aad           []byte
+	src           io.ReaderAt
+	payloadOffset int64
+	chunkSize     int64
+	sealedSize    int64
+	chunks        int64
+	lastSealed    int64
+	size          int64
+
+	lock        sync.Mutex
+	nonce       []byte
+	ciphertext  []byte
+	cached      []byte
+	cachedIndex int64
+}
+
+func (reader *chunkReaderAt) Size() int64 {
+	return reader.size
+}
+
+func (reader *chunkReaderAt) chunk(index int64) ([]byte, error) {
+	if index == reader.cachedIndex {
+		return reader.cached, nil
+	}
+
+	last := index == reader.chunks-1
+	sealed := reader.ciphertext[:reader.sealedSize]
+	if last {
+		sealed = reader.ciphertext[:reader.lastSealed]
+	}
+
+	if _, err := reader.src.ReadAt(sealed, reader.payloadOffset+index*reader.sealedSize); err != nil {
+		return nil, err
+	}
+
+	chunkNonce(reader.nonce, uint64(index), last)
+
+	plaintext, err := reader.aead.Open(reader.cached[:0], reader.nonce, sealed, reader.aad)
+	if err != nil {
+		reader.cachedIndex = -1
+		return nil, fmt.Errorf("chunk [%d] failed authentication", index)
+	}
+
+	reader.cached = plaintext
+	reader.cachedIndex = index
+
+	return plaintext, nil
+}
+
+func (reader *chunkReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	reader.lock.Lock()
+	defer reader.lock.Unlock()
+
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	read := 0
+	for read < len(p) {
+		if off >= reader.size {
+			return read, io.EOF
+		}
+
+		plaintext, err := reader.chunk(off / reader.chunkSize)
+		if err != nil {
+			return read, err
+		}
+
+		n := copy(p[read:], plaintext[off%reader.chunkSize:])
+		read += n
+		off += int64(n)
+	}
+
+	return read, nil
+}
+
+// NewReaderAt decrypts chunks on demand, so callers can read any range of the
+// plaintext without authenticating the rest of the file. The size of src
+// fixes where the final chunk is, so truncation is still detected.
+func NewReaderAt(src io.ReaderAt, size int64, identities ...Identity) (SizedReaderAt, error) {
+	counter := &countingReader{src: io.NewSectionReader(src, 0, size)}
+
+	parsed, err := readHeader(counter)
+	if err != nil {
+		return nil, err
+	}
+
+	header := parsed.header
+	if header.Version == 1 {
+		return nil, errors.New("version 1 files do not support random access")
+	}
+
+	fileKey, err := parsed.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newPayloadAEAD(header, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	payloadSize := size - counter.read
+	chunks, lastSealed, err := chunkLayout(header.ChunkSize, payloadSize)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedSize := int64(header.ChunkSize) + tagSize
+
+	return &chunkReaderAt{
+		aead:          aead,
+		aad:           header.Metadata,
+		src:           src,
+		payloadOffset: counter.read,
+		chunkSize:     int64(header.ChunkSize),
+		sealedSize:    sealedSize,
+		chunks:        chunks,
+		lastSealed:    lastSealed,
+		size:          payloadSize - chunks*tagSize,
+		nonce:         make([]byte, aead.NonceSize()),
+		ciphertext:    make([]byte, sealedSize),
+		cached:        make([]byte, 0, header.ChunkSize),
+		cachedIndex:   -1,
+	}, nil
+}
+
+type countingReader struct {
+	src  io.Reader
+	read int64
+}
+
+func (reader *countingReader) Read(p []byte) (int, error) {
+	n, err := reader.src.Read(p)
+	reader.read += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,59 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+func Test_CanReadRangesAcrossChunks(t *testing.T) {
+	plaintext := make([]byte, 3*fileformat.DefaultChunkSize+123)
+	for i := range plaintext {
+		plaintext[i] = byte(i % 251)
+	}
+
+	encrypted := encryptForTest(t, plaintext, testkeys.NewRecipient(1))
+
+	reader, err := fileformat.NewReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log("NewReaderAt failed", err)
+		t.FailNow()
+	}
+
+	if reader.Size() != int64(len(plaintext)) {
+		t.Log(fmt.Sprintf("Expected size [%d] received [%d]", len(plaintext), reader.Size()))
+		t.Fail()
+	}
+
+	for _, offset := range []int{0, 10, fileformat.DefaultChunkSize - 5, 2 * fileformat.DefaultChunkSize, len(plaintext) - 50} {
+		buffer := make([]byte, 50)
+		if _, err := reader.ReadAt(buffer, int64(offset)); err != nil && err != io.EOF {
+			t.Log(fmt.Sprintf("ReadAt [%d] failed %s", offset, err))
+			t.FailNow()
+		}
+
+		if !bytes.Equal(buffer, plaintext[offset:offset+50]) {
+			t.Log(fmt.Sprintf("Unexpected bytes at offset [%d]", offset))
+			t.Fail()
+		}
+	}
+}
+
+func Test_RandomAccessDetectsTruncation(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+	truncated := encrypted[:headerSize+2*sealedChunkSize]
+
+	reader, err := fileformat.NewReaderAt(bytes.NewReader(truncated), int64(len(truncated)), testkeys.NewIdentity(1))
+	if err != nil {
+		return
+	}
+
+	if _, err = reader.ReadAt(make([]byte, 1), int64(fileformat.DefaultChunkSize+1)); err == nil {
+		t.Log("Expected reading the new final chunk to fail")
+		t.Fail()
+	}
+}
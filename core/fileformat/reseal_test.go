@@ -0,0 +1,69 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+func Test_ResealKeepsRecipientsAndCipher(t *testing.T) {
+	original := bytes.NewBuffer(nil)
+	options := fileformat.Options{Cipher: fileformat.CipherChaCha20Poly1305}
+
+	writer, err := fileformat.NewWriterWithOptions(original, options, testkeys.NewRecipient(1), testkeys.NewRecipient(2))
+	if err != nil {
+		t.Log("NewWriterWithOptions failed", err)
+		t.FailNow()
+	}
+	writer.Write([]byte("first draft"))
+	writer.Close()
+
+	reader, err := fileformat.NewReader(bytes.NewReader(original.Bytes()), testkeys.NewIdentity(1))
+	if err != nil {
+		t.Log("NewReader failed", err)
+		t.FailNow()
+	}
+	ioutil.ReadAll(reader)
+
+	resealed := bytes.NewBuffer(nil)
+	writer, err = reader.Reseal(resealed, nil)
+	if err != nil {
+		t.Log("Reseal failed", err)
+		t.FailNow()
+	}
+	writer.Write([]byte("second draft"))
+	if err = writer.Close(); err != nil {
+		t.Log("Close failed", err)
+		t.FailNow()
+	}
+
+	decrypted, err := decryptForTest(resealed.Bytes(), testkeys.NewIdentity(2))
+	if err != nil {
+		t.Log("The other recipient could not decrypt the resealed file", err)
+		t.FailNow()
+	}
+
+	if string(decrypted) != "second draft" {
+		t.Log("Unexpected plaintext " + string(decrypted))
+		t.Fail()
+	}
+
+	header, err := fileformat.ReadHeader(bytes.NewReader(resealed.Bytes()))
+	if err != nil {
+		t.Log("ReadHeader failed", err)
+		t.FailNow()
+	}
+
+	if header.Cipher != fileformat.CipherChaCha20Poly1305 || len(header.Stanzas) != 2 {
+		t.Log("Expected the cipher and both stanzas to be kept")
+		t.Fail()
+	}
+
+	if bytes.Equal(header.Nonce, reader.Header().Nonce) {
+		t.Log("Expected a fresh header nonce")
+		t.Fail()
+	}
+}
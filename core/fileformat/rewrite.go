@@ -0,0 +1,82 @@
+package fileformat
+
+import (
+	"errors"
+	"io"
+)
+
+type StanzaRewriter func(fileKey []byte, stanzas []Stanza) ([]Stanza, error)
+
+func ReadHeader(src io.Reader) (Header, error) {
+	parsed, err := readHeader(src)
+	if err != nil {
+		return Header{}, err
+	}
+
+	return parsed.header, nil
+}
+
+// Rewrite copies an encrypted file from src to dst, replacing its stanzas with
+// the result of rewrite. The payload is copied untouched, so this is cheap for
+// large files, but a recipient that is removed and has already seen the file
+// key can still decrypt the payload.
+func Rewrite(dst io.Writer, src io.Reader, identities []Identity, rewrite StanzaRewriter) error {
+	parsed, err := readHeader(src)
+	if err != nil {
+		return err
+	}
+
+	fileKey, err := parsed.unwrap(identities)
+	if err != nil {
+		return err
+	}
+
+	header := parsed.header
+	header.Stanzas, err = rewrite(fileKey, append([]Stanza{}, header.Stanzas...))
+	if err != nil {
+		return err
+	}
+
+	if len(header.Stanzas) == 0 {
+		return errors.New("a file must keep at least one stanza")
+	}
+
+	if err = writeHeader(dst, header, fileKey); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func AddRecipients(recipients ...Recipient) StanzaRewriter {
+	return func(fileKey []byte, stanzas []Stanza) ([]Stanza, error) {
+		for _, recipient := range recipients {
+			stanza, err := recipient.Wrap(fileKey)
+			if err != nil {
+				return nil, err
+			}
+
+			stanzas = append(stanzas, stanza)
+		}
+
+		return stanzas, nil
+	}
+}
+
+func RemoveStanzas(matches func(index int, stanza Stanza) bool) StanzaRewriter {
+	return func(fileKey []byte, stanzas []Stanza) ([]Stanza, error) {
+		kept := []Stanza{}
+		for index, stanza := range stanzas {
+			if !matches(index, stanza) {
+				kept = append(kept, stanza)
+			}
+		}
+
+		if len(kept) == len(stanzas) {
+			return nil, errors.New("no stanza matched the recipients to remove")
+		}
+
+		return kept, nil
+	}
+}
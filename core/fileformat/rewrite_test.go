@@ -0,0 +1,78 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+func rewriteForTest(t *testing.T, encrypted []byte, identity fileformat.Identity, rewrite fileformat.StanzaRewriter) []byte {
+	rewritten := bytes.NewBuffer(nil)
+
+	err := fileformat.Rewrite(rewritten, bytes.NewReader(encrypted), []fileformat.Identity{identity}, rewrite)
+	if err != nil {
+		t.Log("Rewrite failed", err)
+		t.FailNow()
+	}
+
+	return rewritten.Bytes()
+}
+
+func Test_AddedRecipientCanDecrypt(t *testing.T) {
+	plaintext := bytes.Repeat([]byte{'z'}, fileformat.DefaultChunkSize+1)
+	encrypted := encryptForTest(t, plaintext, testkeys.NewRecipient(1))
+
+	rewritten := rewriteForTest(t, encrypted, testkeys.NewIdentity(1), fileformat.AddRecipients(testkeys.NewRecipient(2)))
+
+	decrypted, err := decryptForTest(rewritten, testkeys.NewIdentity(2))
+	if err != nil {
+		t.Log("The added recipient could not decrypt", err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Log("Expected the decrypted bytes to match the plaintext")
+		t.Fail()
+	}
+
+	payload := encrypted[len(encrypted)-(sealedChunkSize+1+16):]
+	if !bytes.HasSuffix(rewritten, payload) {
+		t.Log("Expected the payload to be copied without re-encryption")
+		t.Fail()
+	}
+}
+
+func Test_RemovedRecipientCannotDecrypt(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("rotating team"), testkeys.NewRecipient(1), testkeys.NewRecipient(2))
+
+	rewritten := rewriteForTest(t, encrypted, testkeys.NewIdentity(1), fileformat.RemoveStanzas(func(index int, stanza fileformat.Stanza) bool {
+		return index == 1
+	}))
+
+	if _, err := decryptForTest(rewritten, testkeys.NewIdentity(2)); err == nil {
+		t.Log("Expected the removed recipient to fail to decrypt")
+		t.Fail()
+	}
+
+	if _, err := decryptForTest(rewritten, testkeys.NewIdentity(1)); err != nil {
+		t.Log("Expected the remaining recipient to decrypt", err)
+		t.Fail()
+	}
+}
+
+func Test_CannotRemoveEveryStanza(t *testing.T) {
+	encrypted := encryptForTest(t, []byte("last one"), testkeys.NewRecipient(1))
+
+	err := fileformat.Rewrite(
+		bytes.NewBuffer(nil),
+		bytes.NewReader(encrypted),
+		[]fileformat.Identity{testkeys.NewIdentity(1)},
+		fileformat.RemoveStanzas(func(int, fileformat.Stanza) bool { return true }),
+	)
+	if err == nil {
+		t.Log("Expected removing every stanza to fail")
+		t.Fail()
+	}
+}
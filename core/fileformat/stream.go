@@ -0,0 +1,192 @@
+package fileformat
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultChunkSize = 64 * 1024
+	minChunkSize     = 1024
+	maxChunkSize     = 16 * 1024 * 1024
+)
+
+var ErrTruncated = errors.New("encrypted stream is truncated")
+
+// Each chunk is sealed with a nonce made of a big endian chunk counter followed
+// by a single flag byte that is set only for the final chunk, so reordered,
+// dropped or truncated chunks fail to authenticate.
+func chunkNonce(nonce []byte, counter uint64, last bool) {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+func validateChunkSize(chunkSize int) error {
+	if chunkSize < minChunkSize || chunkSize > maxChunkSize {
+		return fmt.Errorf("chunk size [%d] must be between [%d] and [%d]", chunkSize, minChunkSize, maxChunkSize)
+	}
+
+	return nil
+}
+
+type streamWriter struct {
+	aead    cipher.AEAD
+	aad     []byte
+	dst     io.Writer
+	buffer  []byte
+	nonce   []byte
+	counter uint64
+	closed  bool
+}
+
+func (writer *streamWriter) sealChunk(last bool) error {
+	chunkNonce(writer.nonce, writer.counter, last)
+
+	sealed := writer.aead.Seal(nil, writer.nonce, writer.buffer, writer.aad)
+	if _, err := writer.dst.Write(sealed); err != nil {
+		return err
+	}
+
+	writer.buffer = writer.buffer[:0]
+	writer.counter += 1
+
+	return nil
+}
+
+func (writer *streamWriter) Write(p []byte) (int, error) {
+	if writer.closed {
+		return 0, errors.New("write to a closed encrypted stream")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full buffer is only sealed once more data arrives, so the final
+		// chunk is never empty unless the whole stream is.
+		if len(writer.buffer) == cap(writer.buffer) {
+			if err := writer.sealChunk(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(writer.buffer[len(writer.buffer):cap(writer.buffer)], p)
+		writer.buffer = writer.buffer[:len(writer.buffer)+n]
+		written += n
+		p = p[n:]
+	}
+
+	return written, nil
+}
+
+func (writer *streamWriter) Close() error {
+	if writer.closed {
+		return nil
+	}
+
+	writer.closed = true
+
+	return writer.sealChunk(true)
+}
+
+func newStreamWriter(aead cipher.AEAD, dst io.Writer, chunkSize int, aad []byte) io.WriteCloser {
+	return &streamWriter{
+		aead:   aead,
+		aad:    aad,
+		dst:    dst,
+		buffer: make([]byte, 0, chunkSize),
+		nonce:  make([]byte, aead.NonceSize()),
+	}
+}
+
+type streamReader struct {
+	aead       cipher.AEAD
+	aad        []byte
+	src        *bufio.Reader
+	ciphertext []byte
+	buffer     []byte
+	plaintext  []byte
+	nonce      []byte
+	counter    uint64
+	done       bool
+	err        error
+}
+
+func (reader *streamReader) readChunk() error {
+	n, err := io.ReadFull(reader.src, reader.ciphertext)
+
+	last := false
+	switch {
+	case err == io.EOF:
+		return ErrTruncated
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err = reader.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	chunkNonce(reader.nonce, reader.counter, last)
+
+	plaintext, err := reader.aead.Open(reader.buffer[:0], reader.nonce, reader.ciphertext[:n], reader.aad)
+	if err != nil {
+		if last {
+			return fmt.Errorf("chunk [%d] failed authentication, the stream may be truncated", reader.counter)
+		}
+		return fmt.Errorf("chunk [%d] failed authentication", reader.counter)
+	}
+
+	if last && len(plaintext) == 0 && reader.counter > 0 {
+		return errors.New("encrypted stream ends with an unexpected empty chunk")
+	}
+
+	reader.plaintext = plaintext
+	reader.counter += 1
+	reader.done = last
+
+	return nil
+}
+
+func (reader *streamReader) Read(p []byte) (int, error) {
+	for len(reader.plaintext) == 0 {
+		if reader.err != nil {
+			return 0, reader.err
+		}
+
+		if reader.done {
+			return 0, io.EOF
+		}
+
+		reader.err = reader.readChunk()
+	}
+
+	n := copy(p, reader.plaintext)
+	reader.plaintext = reader.plaintext[n:]
+
+	return n, nil
+}
+
+func newStreamReader(aead cipher.AEAD, src io.Reader, chunkSize int, aad []byte) io.Reader {
+	return &streamReader{
+		aead:       aead,
+		aad:        aad,
+		src:        bufio.NewReader(src),
+		ciphertext: make([]byte, chunkSize+aead.Overhead()),
+		buffer:     make([]byte, 0, chunkSize),
+		nonce:      make([]byte, aead.NonceSize()),
+	}
+}
@@ -0,0 +1,67 @@
+package fileformat_test
+
+import (
+	"bytes"
+	"testing"
+
+	"util.tim/encrypto/core/fileformat"
+	"util.tim/encrypto/core/internal/testkeys"
+)
+
+const sealedChunkSize = fileformat.DefaultChunkSize + 16
+
+func threeChunkFile(t *testing.T) ([]byte, int) {
+	plaintext := bytes.Repeat([]byte{'y'}, 2*fileformat.DefaultChunkSize+100)
+	encrypted := encryptForTest(t, plaintext, testkeys.NewRecipient(1))
+
+	headerSize := len(encrypted) - (2*sealedChunkSize + 100 + 16)
+
+	return encrypted, headerSize
+}
+
+func expectDecryptFailure(t *testing.T, encrypted []byte, reason string) {
+	if _, err := decryptForTest(encrypted, testkeys.NewIdentity(1)); err == nil {
+		t.Log("Expected decryption to fail when " + reason)
+		t.Fail()
+	}
+}
+
+func Test_TruncatedAtChunkBoundaryFails(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+
+	expectDecryptFailure(t, encrypted[:headerSize+sealedChunkSize], "the stream stops after a full chunk")
+	expectDecryptFailure(t, encrypted[:headerSize], "the stream has no chunks")
+}
+
+func Test_TruncatedMidChunkFails(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+
+	expectDecryptFailure(t, encrypted[:headerSize+sealedChunkSize+10], "the stream stops inside a chunk")
+}
+
+func Test_ReorderedChunksFail(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+
+	first := encrypted[headerSize : headerSize+sealedChunkSize]
+	second := encrypted[headerSize+sealedChunkSize : headerSize+2*sealedChunkSize]
+
+	reordered := append([]byte{}, encrypted[:headerSize]...)
+	reordered = append(reordered, second...)
+	reordered = append(reordered, first...)
+	reordered = append(reordered, encrypted[headerSize+2*sealedChunkSize:]...)
+
+	expectDecryptFailure(t, reordered, "chunks are swapped")
+}
+
+func Test_TrailingDataFails(t *testing.T) {
+	encrypted, _ := threeChunkFile(t)
+
+	expectDecryptFailure(t, append(encrypted, 0), "data follows the final chunk")
+}
+
+func Test_FlippedBitFails(t *testing.T) {
+	encrypted, headerSize := threeChunkFile(t)
+	encrypted[headerSize+5] ^= 1
+
+	expectDecryptFailure(t, encrypted, "a chunk is modified")
+}
@@ -0,0 +1,108 @@
+package fileformat
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// WrapKey seals a file key under a key encryption key. Every key encryption
+// key is expected to be unique to a single stanza, so a fixed nonce is safe.
+func WrapKey(kek []byte, fileKey []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nil, make([]byte, gcm.NonceSize()), fileKey, nil), nil
+}
+
+func UnwrapKey(kek []byte, wrapped []byte) ([]byte, error) {
+	gcm, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	fileKey, err := gcm.Open(nil, make([]byte, gcm.NonceSize()), wrapped, nil)
+	if err != nil {
+		return nil, ErrIncorrectIdentity
+	}
+
+	if len(fileKey) != FileKeySize {
+		return nil, errors.New("unwrapped file key has the wrong length")
+	}
+
+	return fileKey, nil
+}
+
+// WrapFileKey generates a file key and wraps it for every recipient, for
+// formats other than the file format that still want its stanzas.
+func WrapFileKey(recipients ...Recipient) ([]byte, []Stanza, error) {
+	if len(recipients) == 0 {
+		return nil, nil, errors.New("at least one recipient is required")
+	}
+
+	fileKey := make([]byte, FileKeySize)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, nil, err
+	}
+
+	stanzas := []Stanza{}
+	for _, recipient := range recipients {
+		stanza, err := recipient.Wrap(fileKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stanzas = append(stanzas, stanza)
+	}
+
+	return fileKey, stanzas, nil
+}
+
+// UnwrapFileKey returns the first file key any identity recovers from the
+// stanzas. A stanza is untrusted until verify has checked the key against
+// something authenticated with it, which is why verify is required.
+//
+// An identity may wrap ErrIncorrectIdentity with an explanation, which is
+// reported when no other identity matches.
+func UnwrapFileKey(stanzas []Stanza, identities []Identity, verify func(fileKey []byte) error) ([]byte, error) {
+	var mismatch error
+	for _, identity := range identities {
+		for _, stanza := range stanzas {
+			fileKey, err := identity.Unwrap(stanza)
+			if errors.Is(err, ErrIncorrectIdentity) {
+				if err != ErrIncorrectIdentity {
+					mismatch = err
+				}
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if err = verify(fileKey); err != nil {
+				return nil, err
+			}
+
+			return fileKey, nil
+		}
+	}
+
+	if mismatch != nil {
+		return nil, mismatch
+	}
+
+	return nil, errors.New("no identity matched any of the file's stanzas")
+}
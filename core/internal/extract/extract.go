@@ -0,0 +1,118 @@
+// Package extract writes the entries of archives and backup snapshots to
+// disk, refusing any entry path that could land outside of the target.
+package extract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Entry struct {
+	Path      string
+	Directory bool
+	Mode      os.FileMode
+	ModTime   time.Time
+	// Write copies the contents of a file entry to dst.
+	Write func(dst io.Writer) error
+}
+
+// TargetPath refuses entry paths that could land outside of dir.
+func TargetPath(dir string, entryPath string) (string, error) {
+	if entryPath == "" ||
+		path.IsAbs(entryPath) ||
+		path.Clean(entryPath) != entryPath ||
+		entryPath == ".." ||
+		strings.HasPrefix(entryPath, "../") ||
+		strings.Contains(entryPath, "\\") {
+		return "", fmt.Errorf("refusing to extract unsafe path [%s]", entryPath)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(entryPath)), nil
+}
+
+func extractFile(entry Entry, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = entry.Write(file)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+
+	if err = os.Chmod(target, entry.Mode.Perm()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(target, entry.ModTime, entry.ModTime)
+}
+
+func Extract(entry Entry, dir string) error {
+	target, err := TargetPath(dir, entry.Path)
+	if err != nil {
+		return err
+	}
+
+	if !entry.Directory {
+		return extractFile(entry, target)
+	}
+
+	if err = os.MkdirAll(target, 0700); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(target, entry.Mode.Perm()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(target, entry.ModTime, entry.ModTime)
+}
+
+func ExtractAll(entries []Entry, dir string) error {
+	directories := []Entry{}
+
+	for _, entry := range entries {
+		if entry.Directory {
+			target, err := TargetPath(dir, entry.Path)
+			if err != nil {
+				return err
+			}
+
+			if err = os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+
+			directories = append(directories, entry)
+			continue
+		}
+
+		if err := Extract(entry, dir); err != nil {
+			return err
+		}
+	}
+
+	// Directory modes and times are applied last, deepest first, so writing
+	// their contents neither fails on read only modes nor bumps their mtimes.
+	for i := len(directories) - 1; i >= 0; i-- {
+		if err := Extract(directories[i], dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
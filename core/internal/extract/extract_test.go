@@ -0,0 +1,23 @@
+package extract_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"util.tim/encrypto/core/internal/extract"
+)
+
+func Test_TargetPathRefusesEscapes(t *testing.T) {
+	for _, unsafe := range []string{"", "/etc/passwd", "..", "../x", "a/../../x", "a//b", "./a", "a\\..\\x"} {
+		if _, err := extract.TargetPath("out", unsafe); err == nil {
+			t.Log("Expected", unsafe, "to be refused")
+			t.Fail()
+		}
+	}
+
+	target, err := extract.TargetPath("out", "a/b.txt")
+	if err != nil || target != filepath.Join("out", "a", "b.txt") {
+		t.Log("Unexpected target", target, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,50 @@
+// Package testkeys holds the recipient and identity the core tests encrypt
+// with. They wrap file keys under a key of 32 repeated fill bytes, so an
+// identity only opens what a recipient with the same fill sealed.
+package testkeys
+
+import (
+	"bytes"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+type recipient struct {
+	kek []byte
+}
+
+func (recipient *recipient) Wrap(fileKey []byte) (fileformat.Stanza, error) {
+	body, err := fileformat.WrapKey(recipient.kek, fileKey)
+	if err != nil {
+		return fileformat.Stanza{}, err
+	}
+
+	return fileformat.Stanza{
+		Type: "test",
+		Body: body,
+	}, nil
+}
+
+type identity struct {
+	kek []byte
+}
+
+func (identity *identity) Unwrap(stanza fileformat.Stanza) ([]byte, error) {
+	if stanza.Type != "test" {
+		return nil, fileformat.ErrIncorrectIdentity
+	}
+
+	return fileformat.UnwrapKey(identity.kek, stanza.Body)
+}
+
+func newKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, 32)
+}
+
+func NewRecipient(fill byte) fileformat.Recipient {
+	return &recipient{kek: newKey(fill)}
+}
+
+func NewIdentity(fill byte) fileformat.Identity {
+	return &identity{kek: newKey(fill)}
+}
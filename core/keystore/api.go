@@ -0,0 +1,29 @@
+package keystore
+
+import (
+	"time"
+)
+
+const (
+	TypeSecret    = "secret"
+	TypeRSA       = "rsa"
+	TypeEd25519   = "ed25519"
+	TypeRSAPublic = "rsa-public"
+	TypeEdPublic  = "ed25519-public"
+)
+
+// Entry holds key material as text: base64 for secret keys and PEM for
+// everything else.
+type Entry struct {
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Material string    `json:"material"`
+	Created  time.Time `json:"created"`
+}
+
+type Keystore interface {
+	Entries() []Entry
+	Get(name string) (Entry, bool)
+	Add(entry Entry) error
+	Delete(name string) error
+}
@@ -0,0 +1,118 @@
+package keystore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"util.tim/encrypto/core/fileformat"
+)
+
+type document struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+type keystore struct {
+	entries map[string]Entry
+}
+
+func (keystore *keystore) Entries() []Entry {
+	entries := []Entry{}
+	for _, entry := range keystore.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+func (keystore *keystore) Get(name string) (Entry, bool) {
+	entry, found := keystore.entries[name]
+	return entry, found
+}
+
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n")
+}
+
+func (keystore *keystore) Add(entry Entry) error {
+	if !validName(entry.Name) {
+		return fmt.Errorf("key name [%s] must be non empty and contain no whitespace", entry.Name)
+	}
+
+	if _, found := keystore.entries[entry.Name]; found {
+		return fmt.Errorf("a key named [%s] already exists", entry.Name)
+	}
+
+	keystore.entries[entry.Name] = entry
+	return nil
+}
+
+func (keystore *keystore) Delete(name string) error {
+	if _, found := keystore.entries[name]; !found {
+		return fmt.Errorf("there is no key named [%s]", name)
+	}
+
+	delete(keystore.entries, name)
+	return nil
+}
+
+func New() Keystore {
+	return &keystore{entries: map[string]Entry{}}
+}
+
+func Load(src io.Reader, identities ...fileformat.Identity) (Keystore, error) {
+	reader, err := fileformat.NewReader(src, identities...)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded document
+	if err = json.Unmarshal(contents, &loaded); err != nil {
+		return nil, fmt.Errorf("could not parse keystore: %w", err)
+	}
+
+	if loaded.Version != 1 {
+		return nil, fmt.Errorf("unsupported keystore version [%d]", loaded.Version)
+	}
+
+	keystore := &keystore{entries: map[string]Entry{}}
+	for _, entry := range loaded.Entries {
+		keystore.entries[entry.Name] = entry
+	}
+
+	return keystore, nil
+}
+
+func Save(dst io.Writer, keystore Keystore, recipients ...fileformat.Recipient) error {
+	contents, err := json.Marshal(document{
+		Version: 1,
+		Entries: keystore.Entries(),
+	})
+	if err != nil {
+		return err
+	}
+
+	writer, err := fileformat.NewWriter(dst, recipients...)
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(contents); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,89 @@
+package keystore_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/internal/testkeys"
+	"util.tim/encrypto/core/keystore"
+)
+
+func newTestEntry(name string) keystore.Entry {
+	return keystore.Entry{
+		Name:     name,
+		Type:     keystore.TypeSecret,
+		Material: "c2VjcmV0",
+		Created:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func Test_CanSaveAndLoad(t *testing.T) {
+	store := keystore.New()
+	store.Add(newTestEntry("beta"))
+	store.Add(newTestEntry("alpha"))
+
+	saved := bytes.NewBuffer(nil)
+	if err := keystore.Save(saved, store, testkeys.NewRecipient(7)); err != nil {
+		t.Log("Save failed", err)
+		t.FailNow()
+	}
+
+	if bytes.Contains(saved.Bytes(), []byte("c2VjcmV0")) {
+		t.Log("Expected key material not to be stored in the clear")
+		t.Fail()
+	}
+
+	loaded, err := keystore.Load(bytes.NewReader(saved.Bytes()), testkeys.NewIdentity(7))
+	if err != nil {
+		t.Log("Load failed", err)
+		t.FailNow()
+	}
+
+	names := []string{}
+	for _, entry := range loaded.Entries() {
+		names = append(names, entry.Name)
+	}
+
+	if fmt.Sprint(names) != "[alpha beta]" {
+		t.Log(fmt.Sprintf("Expected sorted names received %v", names))
+		t.Fail()
+	}
+
+	entry, found := loaded.Get("alpha")
+	if !found || entry.Material != "c2VjcmV0" {
+		t.Log("Expected alpha to keep its material")
+		t.Fail()
+	}
+}
+
+func Test_RejectsDuplicateNames(t *testing.T) {
+	store := keystore.New()
+	store.Add(newTestEntry("alpha"))
+
+	if err := store.Add(newTestEntry("alpha")); err == nil {
+		t.Log("Expected a duplicate name to be rejected")
+		t.Fail()
+	}
+}
+
+func Test_CanDelete(t *testing.T) {
+	store := keystore.New()
+	store.Add(newTestEntry("alpha"))
+
+	if err := store.Delete("alpha"); err != nil {
+		t.Log("Delete failed", err)
+		t.FailNow()
+	}
+
+	if _, found := store.Get("alpha"); found {
+		t.Log("Expected alpha to be gone")
+		t.Fail()
+	}
+
+	if err := store.Delete("alpha"); err == nil {
+		t.Log("Expected deleting a missing key to fail")
+		t.Fail()
+	}
+}
@@ -0,0 +1,102 @@
+package mnemonic
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	MinSize = 16
+	MaxSize = 32
+)
+
+var (
+	words   = strings.Split(strings.TrimSpace(english), "\n")
+	indexes = map[string]int{}
+)
+
+func init() {
+	for index, word := range words {
+		indexes[word] = index
+		// BIP39 words are unique in their first four letters, which is
+		// all anyone needs to write down.
+		if len(word) > 4 {
+			indexes[word[:4]] = index
+		}
+	}
+}
+
+func checksumBits(size int) int {
+	return size * 8 / 32
+}
+
+func validSize(size int) error {
+	if size < MinSize || size > MaxSize || size%4 != 0 {
+		return fmt.Errorf("[%d] bytes can not be written as words, expected a multiple of 4 between [%d] and [%d]", size, MinSize, MaxSize)
+	}
+
+	return nil
+}
+
+// Encode writes entropy as BIP39 words: the bits of the entropy followed by
+// the first bits of its SHA-256, eleven bits to a word.
+func Encode(entropy []byte) ([]string, error) {
+	if err := validSize(len(entropy)); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(entropy)
+	bits := append(append([]byte{}, entropy...), sum[0])
+	totalBits := len(entropy)*8 + checksumBits(len(entropy))
+
+	bit := func(position int) int {
+		return int(bits[position/8]>>(7-uint(position%8))) & 1
+	}
+
+	encoded := make([]string, totalBits/11)
+	for i := range encoded {
+		index := 0
+		for j := 0; j < 11; j++ {
+			index = index<<1 | bit(i*11+j)
+		}
+
+		encoded[i] = words[index]
+	}
+
+	return encoded, nil
+}
+
+// Decode accepts whole words or their first four letters, in any case.
+func Decode(phrase []string) ([]byte, error) {
+	totalBits := len(phrase) * 11
+	size := totalBits * 32 / 33 / 8
+	if err := validSize(size); err != nil || totalBits != size*8+checksumBits(size) {
+		return nil, fmt.Errorf("[%d] words is not a valid phrase length", len(phrase))
+	}
+
+	bits := make([]byte, size+1)
+	for i, word := range phrase {
+		index, found := indexes[strings.ToLower(word)]
+		if !found {
+			return nil, fmt.Errorf("word [%d] [%s] is not in the word list", i+1, word)
+		}
+
+		for j := 0; j < 11; j++ {
+			if index>>(10-uint(j))&1 == 1 {
+				position := i*11 + j
+				bits[position/8] |= 1 << (7 - uint(position%8))
+			}
+		}
+	}
+
+	entropy := bits[:size]
+	sum := sha256.Sum256(entropy)
+	mask := byte(0xff << (8 - uint(checksumBits(size))))
+	if sum[0]&mask != bits[size]&mask {
+		return nil, errors.New("the checksum does not match, a word is wrong or out of order")
+	}
+
+	return entropy, nil
+}
@@ -0,0 +1,85 @@
+package mnemonic_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/mnemonic"
+)
+
+// Vectors from the BIP39 reference implementation.
+var vectors = [][2]string{
+	{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+	{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+	{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+	{"0000000000000000000000000000000000000000000000000000000000000000", strings.Repeat("abandon ", 23) + "art"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth useful legal winner thank year wave sausage worth title"},
+	{"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", strings.Repeat("zoo ", 23) + "vote"},
+}
+
+func Test_MatchesReferenceVectors(t *testing.T) {
+	for _, vector := range vectors {
+		entropy, _ := hex.DecodeString(vector[0])
+
+		encoded, err := mnemonic.Encode(entropy)
+		if err != nil {
+			t.Log("Encode failed", err)
+			t.FailNow()
+		}
+
+		if strings.Join(encoded, " ") != vector[1] {
+			t.Log(fmt.Sprintf("Expected [%s] received [%s]", vector[1], strings.Join(encoded, " ")))
+			t.Fail()
+		}
+
+		decoded, err := mnemonic.Decode(strings.Fields(vector[1]))
+		if err != nil {
+			t.Log("Decode failed", err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(decoded, entropy) {
+			t.Log(fmt.Sprintf("Expected [%s] to decode to [%s]", vector[1], vector[0]))
+			t.Fail()
+		}
+	}
+}
+
+func Test_AcceptsPrefixesInAnyCase(t *testing.T) {
+	decoded, err := mnemonic.Decode(strings.Fields("LEGA winn than year wave saus wort usef lega winn than yell"))
+	if err != nil {
+		t.Log("Decode failed", err)
+		t.FailNow()
+	}
+
+	if hex.EncodeToString(decoded) != "7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f" {
+		t.Log("Unexpected entropy " + hex.EncodeToString(decoded))
+		t.Fail()
+	}
+}
+
+func Test_RejectsMistakes(t *testing.T) {
+	for _, phrase := range []string{
+		"legal winner thank year wave sausage worth useful legal winner yellow thank",
+		"legal winner thank year wave sausage worth useful legal winner thank",
+		"legal winner thank year wave sausage worth useful legal winner thank yelow",
+	} {
+		if _, err := mnemonic.Decode(strings.Fields(phrase)); err == nil {
+			t.Log(fmt.Sprintf("Expected [%s] to be rejected", phrase))
+			t.Fail()
+		}
+	}
+}
+
+func Test_RejectsUnsupportedSizes(t *testing.T) {
+	for _, size := range []int{0, 15, 17, 33, 64} {
+		if _, err := mnemonic.Encode(make([]byte, size)); err == nil {
+			t.Log(fmt.Sprintf("Expected [%d] bytes to be rejected", size))
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package otp
+
+import (
+	"time"
+)
+
+const (
+	TypeTOTP = "totp"
+	TypeHOTP = "hotp"
+
+	HashSHA1   = "sha1"
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+
+	MinDigits     = 6
+	MaxDigits     = 9
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+	// MaxSkew bounds the codes Verify and VerifyHOTP try, each costs an HMAC.
+	MaxSkew = 100
+	// MaxPeriod keeps periods far from overflowing a time.Duration.
+	MaxPeriod = 24 * time.Hour
+
+	// DefaultSecretSize is the 160 bits RFC 4226 recommends.
+	DefaultSecretSize = 20
+)
+
+var Hashes = []string{HashSHA1, HashSHA256, HashSHA512}
+
+// Params are shared by both sides. The zero Epoch is the Unix epoch, the T0
+// of RFC 6238.
+type Params struct {
+	Hash   string
+	Digits int
+	Period time.Duration
+	Epoch  time.Time
+}
+
+// DefaultParams are what authenticator apps assume when nothing else is
+// said: SHA-1, 6 digits and a 30 second period.
+func DefaultParams() Params {
+	return Params{Hash: HashSHA1, Digits: DefaultDigits, Period: DefaultPeriod}
+}
+
+// Key is what an otpauth URI carries to an authenticator app.
+type Key struct {
+	Type    string
+	Issuer  string
+	Account string
+	Secret  []byte
+	Params  Params
+	// Counter is the next HOTP counter, and unused for TOTP.
+	Counter uint64
+}
+
+type Generator interface {
+	Params() Params
+	// HOTP is the RFC 4226 code for counter.
+	HOTP(counter uint64) string
+	// TOTP is the RFC 6238 code for the period that holds at.
+	TOTP(at time.Time) string
+	// Counter is the number of periods since the epoch at at.
+	Counter(at time.Time) uint64
+	// Remaining is how long the TOTP code of at stays current.
+	Remaining(at time.Time) time.Duration
+	// Verify accepts the TOTP code of at and of up to skew periods either
+	// side of it, comparing in constant time. A skew outside of 0 to
+	// MaxSkew accepts nothing.
+	Verify(code string, at time.Time, skew int) bool
+	// VerifyHOTP accepts the codes for counter up to counter+lookAhead and
+	// returns the counter that matched, so the caller can move past it. A
+	// lookAhead outside of 0 to MaxSkew accepts nothing.
+	VerifyHOTP(code string, counter uint64, lookAhead int) (uint64, bool)
+}
@@ -0,0 +1,158 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math"
+	"time"
+)
+
+type generator struct {
+	secret  []byte
+	params  Params
+	newHash func() hash.Hash
+	modulus uint32
+}
+
+func hashFor(name string) (func() hash.Hash, error) {
+	switch name {
+	case HashSHA1:
+		return sha1.New, nil
+	case HashSHA256:
+		return sha256.New, nil
+	case HashSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported hash [%s]", name)
+	}
+}
+
+func New(secret []byte, params Params) (Generator, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("the secret must not be empty")
+	}
+
+	if params.Digits < MinDigits || params.Digits > MaxDigits {
+		return nil, fmt.Errorf("codes must have between [%d] and [%d] digits, received [%d]", MinDigits, MaxDigits, params.Digits)
+	}
+
+	if params.Period < time.Second || params.Period > MaxPeriod || params.Period%time.Second != 0 {
+		return nil, fmt.Errorf("the period must be a whole number of seconds up to [%s], received [%s]", MaxPeriod, params.Period)
+	}
+
+	newHash, err := hashFor(params.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Epoch.IsZero() {
+		params.Epoch = time.Unix(0, 0)
+	}
+
+	modulus := uint32(1)
+	for i := 0; i < params.Digits; i++ {
+		modulus *= 10
+	}
+
+	return &generator{
+		secret:  secret,
+		params:  params,
+		newHash: newHash,
+		modulus: modulus,
+	}, nil
+}
+
+func (generator *generator) Params() Params {
+	return generator.params
+}
+
+// HOTP follows the dynamic truncation of RFC 4226 section 5.3.
+func (generator *generator) HOTP(counter uint64) string {
+	mac := hmac.New(generator.newHash, generator.secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", generator.params.Digits, truncated%generator.modulus)
+}
+
+// Counter treats times before the epoch as the first period. It counts in
+// seconds, since a Duration only spans a few centuries.
+func (generator *generator) Counter(at time.Time) uint64 {
+	elapsed := at.Unix() - generator.params.Epoch.Unix()
+	if elapsed < 0 {
+		return 0
+	}
+
+	return uint64(elapsed / int64(generator.params.Period/time.Second))
+}
+
+func (generator *generator) Remaining(at time.Time) time.Duration {
+	next := generator.params.Epoch.Add(time.Duration(generator.Counter(at)+1) * generator.params.Period)
+
+	return next.Sub(at)
+}
+
+func (generator *generator) TOTP(at time.Time) string {
+	return generator.HOTP(generator.Counter(at))
+}
+
+// matches compares against every candidate without stopping at the first
+// match, so the time taken says nothing about which one matched.
+func (generator *generator) matches(code string, first uint64, last uint64) (uint64, bool) {
+	matched, found := uint64(0), 0
+	for counter := first; ; counter++ {
+		equal := subtle.ConstantTimeCompare([]byte(code), []byte(generator.HOTP(counter)))
+		if equal == 1 && found == 0 {
+			matched = counter
+		}
+		found |= equal
+
+		if counter == last {
+			break
+		}
+	}
+
+	return matched, found == 1
+}
+
+// upTo adds n to counter, stopping at the last counter instead of wrapping.
+func upTo(counter uint64, n uint64) uint64 {
+	if counter > math.MaxUint64-n {
+		return math.MaxUint64
+	}
+
+	return counter + n
+}
+
+func (generator *generator) Verify(code string, at time.Time, skew int) bool {
+	if skew < 0 || skew > MaxSkew {
+		return false
+	}
+
+	counter := generator.Counter(at)
+	first := uint64(0)
+	if counter > uint64(skew) {
+		first = counter - uint64(skew)
+	}
+
+	_, found := generator.matches(code, first, upTo(counter, uint64(skew)))
+	return found
+}
+
+func (generator *generator) VerifyHOTP(code string, counter uint64, lookAhead int) (uint64, bool) {
+	if lookAhead < 0 || lookAhead > MaxSkew {
+		return 0, false
+	}
+
+	return generator.matches(code, counter, upTo(counter, uint64(lookAhead)))
+}
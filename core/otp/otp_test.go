@@ -0,0 +1,185 @@
+package otp_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/otp"
+)
+
+// The seeds of RFC 6238 appendix B, one per hash. RFC 4226 uses the SHA-1
+// seed as well.
+var seeds = map[string][]byte{
+	otp.HashSHA1:   []byte("12345678901234567890"),
+	otp.HashSHA256: []byte("12345678901234567890123456789012"),
+	otp.HashSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+}
+
+func newForTest(t *testing.T, hash string, digits int) otp.Generator {
+	params := otp.DefaultParams()
+	params.Hash = hash
+	params.Digits = digits
+
+	generator, err := otp.New(seeds[hash], params)
+	if err != nil {
+		t.Log("New failed", err)
+		t.FailNow()
+	}
+
+	return generator
+}
+
+func Test_RFC4226Vectors(t *testing.T) {
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	generator := newForTest(t, otp.HashSHA1, 6)
+
+	for counter, code := range expected {
+		if actual := generator.HOTP(uint64(counter)); actual != code {
+			t.Log("Counter", counter, "expected", code, "received", actual)
+			t.Fail()
+		}
+	}
+}
+
+func Test_RFC6238Vectors(t *testing.T) {
+	vectors := []struct {
+		seconds int64
+		codes   map[string]string
+	}{
+		{59, map[string]string{otp.HashSHA1: "94287082", otp.HashSHA256: "46119246", otp.HashSHA512: "90693936"}},
+		{1111111109, map[string]string{otp.HashSHA1: "07081804", otp.HashSHA256: "68084774", otp.HashSHA512: "25091201"}},
+		{1111111111, map[string]string{otp.HashSHA1: "14050471", otp.HashSHA256: "67062674", otp.HashSHA512: "99943326"}},
+		{1234567890, map[string]string{otp.HashSHA1: "89005924", otp.HashSHA256: "91819424", otp.HashSHA512: "93441116"}},
+		{2000000000, map[string]string{otp.HashSHA1: "69279037", otp.HashSHA256: "90698825", otp.HashSHA512: "38618901"}},
+		{20000000000, map[string]string{otp.HashSHA1: "65353130", otp.HashSHA256: "77737706", otp.HashSHA512: "47863826"}},
+	}
+
+	for _, hash := range otp.Hashes {
+		generator := newForTest(t, hash, 8)
+
+		for _, vector := range vectors {
+			at := time.Unix(vector.seconds, 0)
+			if actual := generator.TOTP(at); actual != vector.codes[hash] {
+				t.Log(hash, "at", vector.seconds, "expected", vector.codes[hash], "received", actual)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func Test_VerifyAcceptsSkew(t *testing.T) {
+	generator := newForTest(t, otp.HashSHA256, 6)
+	at := time.Unix(1234567890, 0)
+	code := generator.TOTP(at)
+
+	if !generator.Verify(code, at, 0) {
+		t.Log("Expected the current code to verify")
+		t.Fail()
+	}
+
+	later := at.Add(otp.DefaultPeriod)
+	if generator.Verify(code, later, 0) {
+		t.Log("Expected the previous code to fail without skew")
+		t.Fail()
+	}
+
+	if !generator.Verify(code, later, 1) || !generator.Verify(code, at.Add(-otp.DefaultPeriod), 1) {
+		t.Log("Expected a skew of one to accept neighbouring periods")
+		t.Fail()
+	}
+
+	if generator.Verify(code, at.Add(2*otp.DefaultPeriod), 1) {
+		t.Log("Expected a code two periods away to fail with a skew of one")
+		t.Fail()
+	}
+}
+
+func Test_RemainingCountsDownToTheNextPeriod(t *testing.T) {
+	generator := newForTest(t, otp.HashSHA1, 6)
+
+	if remaining := generator.Remaining(time.Unix(59, 0)); remaining != time.Second {
+		t.Log("Expected one second left at 59, received", remaining)
+		t.Fail()
+	}
+
+	if remaining := generator.Remaining(time.Unix(60, 0)); remaining != otp.DefaultPeriod {
+		t.Log("Expected a whole period left at 60, received", remaining)
+		t.Fail()
+	}
+}
+
+func Test_VerifyRejectsOtherCodes(t *testing.T) {
+	generator := newForTest(t, otp.HashSHA1, 6)
+	at := time.Unix(59, 0)
+	code := generator.TOTP(at)
+
+	for _, wrong := range []string{"", code[:5], code + "0", "000000"} {
+		if wrong != code && generator.Verify(wrong, at, 1) {
+			t.Log("Expected", wrong, "to fail")
+			t.Fail()
+		}
+	}
+}
+
+func Test_VerifyHOTPReturnsMatchedCounter(t *testing.T) {
+	generator := newForTest(t, otp.HashSHA1, 6)
+
+	counter, found := generator.VerifyHOTP("969429", 1, 5)
+	if !found || counter != 3 {
+		t.Log("Expected counter 3, received", counter, found)
+		t.Fail()
+	}
+
+	if _, found = generator.VerifyHOTP("969429", 4, 5); found {
+		t.Log("Expected a code behind the counter to fail")
+		t.Fail()
+	}
+}
+
+func Test_VerifyHOTPDoesNotWrapOrAcceptBadWindows(t *testing.T) {
+	generator := newForTest(t, otp.HashSHA1, 6)
+	last := uint64(math.MaxUint64)
+	code := generator.HOTP(last)
+
+	if counter, found := generator.VerifyHOTP(code, last-1, 5); !found || counter != last {
+		t.Log("Expected the last counter to match without wrapping, received", counter, found)
+		t.Fail()
+	}
+
+	if _, found := generator.VerifyHOTP(generator.HOTP(0), last, 5); found {
+		t.Log("Expected the window not to wrap around to counter 0")
+		t.Fail()
+	}
+
+	if _, found := generator.VerifyHOTP(generator.HOTP(3), 3, -1); found {
+		t.Log("Expected a negative look ahead to accept nothing")
+		t.Fail()
+	}
+
+	if _, found := generator.VerifyHOTP(generator.HOTP(3), 3, otp.MaxSkew+1); found {
+		t.Log("Expected a look ahead beyond MaxSkew to accept nothing")
+		t.Fail()
+	}
+
+	if generator.Verify(generator.TOTP(time.Unix(59, 0)), time.Unix(59, 0), -1) {
+		t.Log("Expected a negative skew to accept nothing")
+		t.Fail()
+	}
+}
+
+func Test_InvalidParamsAreRejected(t *testing.T) {
+	invalid := []otp.Params{
+		{Hash: otp.HashSHA1, Digits: 5, Period: otp.DefaultPeriod},
+		{Hash: otp.HashSHA1, Digits: 10, Period: otp.DefaultPeriod},
+		{Hash: "md5", Digits: 6, Period: otp.DefaultPeriod},
+		{Hash: otp.HashSHA1, Digits: 6, Period: 0},
+	}
+
+	for _, params := range invalid {
+		if _, err := otp.New(seeds[otp.HashSHA1], params); err == nil {
+			t.Log("Expected", params, "to be rejected")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package otp
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func GenerateSecret(size int) ([]byte, error) {
+	if size < 16 {
+		return nil, fmt.Errorf("secrets must be at least [16] bytes, received [%d]", size)
+	}
+
+	secret := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// EncodeSecret writes the unpadded Base32 that authenticator apps expect.
+func EncodeSecret(secret []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+}
+
+// DecodeSecret accepts secrets the way sites show them: in any case, with
+// or without padding, and split into groups by spaces or dashes.
+func DecodeSecret(encoded string) ([]byte, error) {
+	cleaned := strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+
+		return r
+	}, encoded))
+	cleaned = strings.TrimRight(cleaned, "=")
+
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("the secret is not valid Base32: %w", err)
+	}
+
+	if len(secret) == 0 {
+		return nil, errors.New("the secret must not be empty")
+	}
+
+	return secret, nil
+}
+
+func (key Key) Generator() (Generator, error) {
+	return New(key.Secret, key.Params)
+}
+
+// URI writes the otpauth format of Google Authenticator, which other apps
+// follow too. Parameters that hold their defaults are still written, since
+// some apps ignore anything they do not see.
+func (key Key) URI() string {
+	label := key.Account
+	if key.Issuer != "" {
+		label = key.Issuer + ":" + key.Account
+	}
+
+	values := url.Values{}
+	values.Set("secret", EncodeSecret(key.Secret))
+	if key.Issuer != "" {
+		values.Set("issuer", key.Issuer)
+	}
+	values.Set("algorithm", strings.ToUpper(key.Params.Hash))
+	values.Set("digits", strconv.Itoa(key.Params.Digits))
+	if key.Type == TypeHOTP {
+		values.Set("counter", strconv.FormatUint(key.Counter, 10))
+	} else {
+		values.Set("period", strconv.Itoa(int(key.Params.Period/time.Second)))
+	}
+
+	// Several apps decode a + as a space, so spaces are written as %20 and
+	// a literal + as %2B throughout.
+	query := strings.Replace(values.Encode(), "+", "%20", -1)
+	path := strings.Replace(url.PathEscape(label), "+", "%2B", -1)
+
+	return fmt.Sprintf("otpauth://%s/%s?%s", key.Type, path, query)
+}
+
+// parseUint bounds the value before callers convert it, so nothing wraps
+// on the way to a narrower type.
+func parseUint(values url.Values, name string, fallback uint64, max uint64) (uint64, error) {
+	value := values.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed > max {
+		return 0, fmt.Errorf("invalid %s [%s]", name, value)
+	}
+
+	return parsed, nil
+}
+
+func ParseURI(uri string) (Key, error) {
+	parsed, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return Key{}, err
+	}
+
+	if parsed.Scheme != "otpauth" {
+		return Key{}, fmt.Errorf("expected an otpauth URI, received scheme [%s]", parsed.Scheme)
+	}
+
+	key := Key{Type: strings.ToLower(parsed.Host), Params: DefaultParams()}
+	if key.Type != TypeTOTP && key.Type != TypeHOTP {
+		return Key{}, fmt.Errorf("unknown OTP type [%s], expected totp or hotp", parsed.Host)
+	}
+
+	label := strings.TrimPrefix(parsed.Path, "/")
+	if separator := strings.Index(label, ":"); separator >= 0 {
+		key.Issuer = strings.TrimSpace(label[:separator])
+		label = label[separator+1:]
+	}
+	key.Account = strings.TrimSpace(label)
+
+	values := parsed.Query()
+	if issuer := values.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	if values.Get("secret") == "" {
+		return Key{}, errors.New("the URI has no secret")
+	}
+
+	if key.Secret, err = DecodeSecret(values.Get("secret")); err != nil {
+		return Key{}, err
+	}
+
+	if algorithm := values.Get("algorithm"); algorithm != "" {
+		key.Params.Hash = strings.ToLower(algorithm)
+	}
+
+	digits, err := parseUint(values, "digits", uint64(key.Params.Digits), MaxDigits)
+	if err != nil {
+		return Key{}, err
+	}
+	key.Params.Digits = int(digits)
+
+	period, err := parseUint(values, "period", uint64(key.Params.Period/time.Second), uint64(MaxPeriod/time.Second))
+	if err != nil {
+		return Key{}, err
+	}
+	key.Params.Period = time.Duration(period) * time.Second
+
+	if key.Type == TypeHOTP {
+		if values.Get("counter") == "" {
+			return Key{}, errors.New("an hotp URI needs a counter")
+		}
+
+		if key.Counter, err = parseUint(values, "counter", 0, math.MaxUint64); err != nil {
+			return Key{}, err
+		}
+	}
+
+	// Building the generator checks the parameters before anyone relies on
+	// the key.
+	if _, err = key.Generator(); err != nil {
+		return Key{}, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,125 @@
+package otp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"util.tim/encrypto/core/otp"
+)
+
+func Test_SecretsRoundTripThroughBase32(t *testing.T) {
+	secret, err := otp.GenerateSecret(otp.DefaultSecretSize)
+	if err != nil {
+		t.Log("GenerateSecret failed", err)
+		t.FailNow()
+	}
+
+	encoded := otp.EncodeSecret(secret)
+	if strings.Contains(encoded, "=") {
+		t.Log("Expected no padding in", encoded)
+		t.Fail()
+	}
+
+	decoded, err := otp.DecodeSecret(strings.ToLower(encoded))
+	if err != nil || !bytes.Equal(decoded, secret) {
+		t.Log("Decoding failed", err)
+		t.Fail()
+	}
+}
+
+func Test_DecodeSecretAcceptsGroupsAndPadding(t *testing.T) {
+	for _, encoded := range []string{"JBSWY3DPEHPK3PXP", "jbsw y3dp ehpk 3pxp", "JBSW-Y3DP-EHPK-3PXP", "JBSWY3DPEHPK3PXP===="} {
+		decoded, err := otp.DecodeSecret(encoded)
+		if err != nil || string(decoded) != "Hello!\xde\xad\xbe\xef" {
+			t.Log("Could not decode", encoded, err)
+			t.Fail()
+		}
+	}
+
+	if _, err := otp.DecodeSecret("not base32!"); err == nil {
+		t.Log("Expected invalid Base32 to fail")
+		t.Fail()
+	}
+}
+
+func Test_ParsesTheGoogleAuthenticatorExample(t *testing.T) {
+	key, err := otp.ParseURI("otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+	if err != nil {
+		t.Log("ParseURI failed", err)
+		t.FailNow()
+	}
+
+	if key.Type != otp.TypeTOTP || key.Issuer != "Example" || key.Account != "alice@google.com" {
+		t.Log("Unexpected key", key)
+		t.Fail()
+	}
+
+	if key.Params != otp.DefaultParams() {
+		t.Log("Expected the default parameters, received", key.Params)
+		t.Fail()
+	}
+}
+
+func Test_URIRoundTrips(t *testing.T) {
+	keys := []otp.Key{
+		{
+			Type:    otp.TypeTOTP,
+			Issuer:  "Big Corp & Sons",
+			Account: "someone+test@example.com",
+			Secret:  []byte("12345678901234567890"),
+			Params:  otp.Params{Hash: otp.HashSHA512, Digits: 8, Period: 60 * time.Second},
+		},
+		{
+			Type:    otp.TypeHOTP,
+			Account: "no issuer",
+			Secret:  []byte("12345678901234567890"),
+			Params:  otp.DefaultParams(),
+			Counter: 42,
+		},
+	}
+
+	for _, key := range keys {
+		uri := key.URI()
+		if strings.Contains(uri, "+") || strings.Contains(uri, " ") {
+			t.Log("Expected spaces and plus signs to be escaped in", uri)
+			t.Fail()
+		}
+
+		parsed, err := otp.ParseURI(uri)
+		if err != nil {
+			t.Log("ParseURI failed for", uri, err)
+			t.Fail()
+			continue
+		}
+
+		if parsed.Type != key.Type || parsed.Issuer != key.Issuer || parsed.Account != key.Account ||
+			!bytes.Equal(parsed.Secret, key.Secret) || parsed.Params != key.Params || parsed.Counter != key.Counter {
+			t.Log("Round trip changed", key, "into", parsed)
+			t.Fail()
+		}
+	}
+}
+
+func Test_InvalidURIsAreRejected(t *testing.T) {
+	invalid := []string{
+		"https://totp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://motp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/Example:alice",
+		"otpauth://hotp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&digits=18446744073709551615",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&period=86401",
+		"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&period=18446744073",
+	}
+
+	for _, uri := range invalid {
+		if _, err := otp.ParseURI(uri); err == nil {
+			t.Log("Expected", uri, "to be rejected")
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package qr
+
+import (
+	"fmt"
+)
+
+type Level int
+
+const (
+	Low Level = iota
+	Medium
+	Quartile
+	High
+)
+
+const (
+	MinVersion = 1
+	MaxVersion = 40
+	// QuietZone is the light border, in modules, that readers expect around
+	// a code.
+	QuietZone = 4
+)
+
+type Code interface {
+	Version() int
+	Size() int
+	Dark(x int, y int) bool
+}
+
+// Capacity returns how many bytes fit in the largest code at level.
+func Capacity(level Level) int {
+	return dataCapacity(MaxVersion, level)
+}
+
+// Encode builds the smallest byte mode code that holds data at the given
+// error correction level.
+func Encode(data []byte, level Level) (Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("unknown error correction level [%d]", level)
+	}
+
+	for version := MinVersion; version <= MaxVersion; version++ {
+		if len(data) <= dataCapacity(version, level) {
+			return newMatrix(version, level, encodeData(data, version, level)), nil
+		}
+	}
+
+	return nil, fmt.Errorf("[%d] bytes do not fit in a QR code, the maximum at this level is [%d]", len(data), Capacity(level))
+}
@@ -0,0 +1,119 @@
+package qr
+
+// rawDataModules counts the modules left for data and error correction once
+// the function patterns are placed, from ISO/IEC 18004 section 8.
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		alignments := version/7 + 2
+		result -= (25*alignments-10)*alignments - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+
+	return result
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*errorCorrectionBlocks[level][version]
+}
+
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+
+	return 16
+}
+
+// dataCapacity is the number of bytes a byte mode segment can carry: the
+// data codewords less the four bit mode indicator and the character count.
+func dataCapacity(version int, level Level) int {
+	return (dataCodewords(version, level)*8 - 4 - countBits(version)) / 8
+}
+
+type bitBuffer struct {
+	bytes []byte
+	size  int
+}
+
+func (buffer *bitBuffer) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		if buffer.size%8 == 0 {
+			buffer.bytes = append(buffer.bytes, 0)
+		}
+
+		if value>>uint(i)&1 == 1 {
+			buffer.bytes[buffer.size/8] |= 1 << (7 - uint(buffer.size%8))
+		}
+		buffer.size++
+	}
+}
+
+// encodeData returns the final codeword sequence: the padded data split into
+// blocks, each followed by its Reed-Solomon codewords, then interleaved.
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := dataCodewords(version, level)
+
+	buffer := &bitBuffer{}
+	buffer.append(0x4, 4)
+	buffer.append(len(data), countBits(version))
+	for _, b := range data {
+		buffer.append(int(b), 8)
+	}
+
+	terminator := capacity*8 - buffer.size
+	if terminator > 4 {
+		terminator = 4
+	}
+	buffer.append(0, terminator)
+	buffer.append(0, (8-buffer.size%8)%8)
+
+	for pad := 0xec; len(buffer.bytes) < capacity; pad ^= 0xec ^ 0x11 {
+		buffer.append(pad, 8)
+	}
+
+	return interleave(buffer.bytes, version, level)
+}
+
+func interleave(data []byte, version int, level Level) []byte {
+	blockCount := errorCorrectionBlocks[level][version]
+	eccSize := eccCodewordsPerBlock[level][version]
+	raw := rawDataModules(version) / 8
+	shortBlocks := blockCount - raw%blockCount
+	shortSize := raw / blockCount
+
+	generator := divisor(eccSize)
+
+	blocks := make([][]byte, blockCount)
+	eccs := make([][]byte, blockCount)
+	offset := 0
+	for i := range blocks {
+		size := shortSize - eccSize
+		if i >= shortBlocks {
+			size++
+		}
+
+		blocks[i] = data[offset : offset+size]
+		eccs[i] = remainder(blocks[i], generator)
+		offset += size
+	}
+
+	result := make([]byte, 0, raw)
+	for i := 0; i <= shortSize-eccSize; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+
+	for i := 0; i < eccSize; i++ {
+		for _, ecc := range eccs {
+			result = append(result, ecc[i])
+		}
+	}
+
+	return result
+}
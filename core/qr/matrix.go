@@ -0,0 +1,250 @@
+package qr
+
+type matrix struct {
+	version  int
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (matrix *matrix) Version() int {
+	return matrix.version
+}
+
+func (matrix *matrix) Size() int {
+	return matrix.size
+}
+
+func (matrix *matrix) Dark(x int, y int) bool {
+	if x < 0 || y < 0 || x >= matrix.size || y >= matrix.size {
+		return false
+	}
+
+	return matrix.modules[y][x]
+}
+
+func newMatrix(version int, level Level, codewords []byte) *matrix {
+	size := version*4 + 17
+	result := &matrix{version: version, size: size}
+	result.modules = make([][]bool, size)
+	result.function = make([][]bool, size)
+	for y := 0; y < size; y++ {
+		result.modules[y] = make([]bool, size)
+		result.function[y] = make([]bool, size)
+	}
+
+	result.drawFunctionPatterns()
+	result.drawCodewords(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < len(masks); mask++ {
+		result.applyMask(mask)
+		result.drawFormat(level, mask)
+
+		penalty := result.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+
+		// Masking is its own inverse.
+		result.applyMask(mask)
+	}
+
+	result.applyMask(best)
+	result.drawFormat(level, best)
+
+	return result
+}
+
+func (matrix *matrix) set(x int, y int, dark bool) {
+	matrix.modules[y][x] = dark
+	matrix.function[y][x] = true
+}
+
+func (matrix *matrix) drawFunctionPatterns() {
+	for i := 0; i < matrix.size; i++ {
+		matrix.set(6, i, i%2 == 0)
+		matrix.set(i, 6, i%2 == 0)
+	}
+
+	matrix.drawFinder(3, 3)
+	matrix.drawFinder(matrix.size-4, 3)
+	matrix.drawFinder(3, matrix.size-4)
+
+	positions := alignmentPositions(matrix.version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// The corners already hold finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+
+			matrix.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format areas, they are written once the mask is known.
+	matrix.drawFormat(Low, 0)
+	matrix.drawVersion()
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+
+	return value
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// drawFinder draws a finder pattern with its light separator around the
+// centre x, y, clipping whatever falls outside the symbol.
+func (matrix *matrix) drawFinder(x int, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			if x+dx < 0 || x+dx >= matrix.size || y+dy < 0 || y+dy >= matrix.size {
+				continue
+			}
+
+			distance := max(abs(dx), abs(dy))
+			matrix.set(x+dx, y+dy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (matrix *matrix) drawAlignment(x int, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			matrix.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	count := version/7 + 2
+	step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+	if version == 32 {
+		step = 26
+	}
+
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, position := count-1, version*4+10; i > 0; i, position = i-1, position-step {
+		positions[i] = position
+	}
+
+	return positions
+}
+
+func (matrix *matrix) drawFormat(level Level, mask int) {
+	data := formatLevelBits[level]<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = remainder<<1 ^ (remainder>>9)*0x537
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+
+	bit := func(i int) bool {
+		return bits>>uint(i)&1 == 1
+	}
+
+	for i := 0; i <= 5; i++ {
+		matrix.set(8, i, bit(i))
+	}
+	matrix.set(8, 7, bit(6))
+	matrix.set(8, 8, bit(7))
+	matrix.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		matrix.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		matrix.set(matrix.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		matrix.set(8, matrix.size-15+i, bit(i))
+	}
+
+	matrix.set(8, matrix.size-8, true)
+}
+
+func (matrix *matrix) drawVersion() {
+	if matrix.version < 7 {
+		return
+	}
+
+	remainder := matrix.version
+	for i := 0; i < 12; i++ {
+		remainder = remainder<<1 ^ (remainder>>11)*0x1f25
+	}
+	bits := matrix.version<<12 | remainder
+
+	for i := 0; i < 18; i++ {
+		dark := bits>>uint(i)&1 == 1
+		a, b := matrix.size-11+i%3, i/3
+		matrix.set(a, b, dark)
+		matrix.set(b, a, dark)
+	}
+}
+
+// drawCodewords fills the data area in the standard zigzag: two columns at a
+// time from the right, alternately upwards and downwards, skipping the
+// vertical timing pattern.
+func (matrix *matrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := matrix.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+
+		for vertical := 0; vertical < matrix.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = matrix.size - 1 - vertical
+				}
+
+				if matrix.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+
+				matrix.modules[y][x] = codewords[i/8]>>(7-uint(i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+var masks = []func(x int, y int) bool{
+	func(x int, y int) bool { return (x+y)%2 == 0 },
+	func(x int, y int) bool { return y%2 == 0 },
+	func(x int, y int) bool { return x%3 == 0 },
+	func(x int, y int) bool { return (x+y)%3 == 0 },
+	func(x int, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x int, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x int, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x int, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+func (matrix *matrix) applyMask(mask int) {
+	for y := 0; y < matrix.size; y++ {
+		for x := 0; x < matrix.size; x++ {
+			if !matrix.function[y][x] && masks[mask](x, y) {
+				matrix.modules[y][x] = !matrix.modules[y][x]
+			}
+		}
+	}
+}
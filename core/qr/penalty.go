@@ -0,0 +1,92 @@
+package qr
+
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// finderLike is a dark-light-dark-dark-dark-light-dark run with four light
+// modules on one side, which a reader could mistake for a finder pattern.
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores the matrix with the four rules of ISO/IEC 18004 section
+// 7.8.3; the mask with the lowest score is the one that is kept.
+func (matrix *matrix) penalty() int {
+	result := 0
+
+	for i := 0; i < matrix.size; i++ {
+		row := func(j int) bool { return matrix.Dark(j, i) }
+		column := func(j int) bool { return matrix.Dark(i, j) }
+
+		result += matrix.runPenalty(row) + matrix.runPenalty(column)
+		result += matrix.finderPenalty(row) + matrix.finderPenalty(column)
+	}
+
+	dark := 0
+	for y := 0; y < matrix.size; y++ {
+		for x := 0; x < matrix.size; x++ {
+			if matrix.modules[y][x] {
+				dark++
+			}
+
+			if x+1 < matrix.size && y+1 < matrix.size {
+				color := matrix.modules[y][x]
+				if color == matrix.modules[y][x+1] && color == matrix.modules[y+1][x] && color == matrix.modules[y+1][x+1] {
+					result += penaltyBlock
+				}
+			}
+		}
+	}
+
+	total := matrix.size * matrix.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyBalance
+
+	return result
+}
+
+func (matrix *matrix) runPenalty(line func(int) bool) int {
+	result := 0
+	run := 1
+	for j := 1; j <= matrix.size; j++ {
+		if j < matrix.size && line(j) == line(j-1) {
+			run++
+			continue
+		}
+
+		if run >= 5 {
+			result += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	return result
+}
+
+// finderPenalty treats the modules outside the symbol as light, as they are
+// in the quiet zone.
+func (matrix *matrix) finderPenalty(line func(int) bool) int {
+	result := 0
+	for _, pattern := range finderLike {
+		for start := -4; start+len(pattern) <= matrix.size+4; start++ {
+			matched := true
+			for k, dark := range pattern {
+				if line(start+k) != dark {
+					matched = false
+					break
+				}
+			}
+
+			if matched {
+				result += penaltyFinder
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,146 @@
+package qr_test
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/qr"
+)
+
+func Test_PicksTheSmallestVersion(t *testing.T) {
+	cases := []struct {
+		size    int
+		level   qr.Level
+		version int
+	}{
+		{17, qr.Low, 1},
+		{18, qr.Low, 2},
+		{14, qr.Medium, 1},
+		{7, qr.High, 1},
+		{8, qr.High, 2},
+		{2953, qr.Low, 40},
+		{1273, qr.High, 40},
+	}
+
+	for _, c := range cases {
+		code, err := qr.Encode(bytes.Repeat([]byte{'a'}, c.size), c.level)
+		if err != nil {
+			t.Log("Encode failed", err)
+			t.FailNow()
+		}
+
+		if code.Version() != c.version || code.Size() != c.version*4+17 {
+			t.Log(fmt.Sprintf("Expected version [%d] for [%d] bytes, received [%d] with size [%d]", c.version, c.size, code.Version(), code.Size()))
+			t.Fail()
+		}
+	}
+}
+
+func Test_RejectsDataThatDoesNotFit(t *testing.T) {
+	if _, err := qr.Encode(make([]byte, qr.Capacity(qr.Medium)+1), qr.Medium); err == nil {
+		t.Log("Expected an error for data larger than the capacity")
+		t.Fail()
+	}
+}
+
+func Test_DrawsFinderPatterns(t *testing.T) {
+	code, err := qr.Encode([]byte("encrypto"), qr.Medium)
+	if err != nil {
+		t.Log("Encode failed", err)
+		t.FailNow()
+	}
+
+	size := code.Size()
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := dx == 0 || dy == 0 || dx == 6 || dy == 6
+				centre := dx >= 2 && dx <= 4 && dy >= 2 && dy <= 4
+				if code.Dark(corner[0]+dx, corner[1]+dy) != (ring || centre) {
+					t.Log(fmt.Sprintf("Unexpected module in the finder at [%d, %d]", corner[0]+dx, corner[1]+dy))
+					t.FailNow()
+				}
+			}
+		}
+	}
+}
+
+// The expected symbol comes from an independent encoder, which happens to
+// pick the same mask for this input.
+func Test_MatchesKnownSymbol(t *testing.T) {
+	expected := []string{
+		"#######..#.##.#######",
+		"#.....#...#...#.....#",
+		"#.###.#.####..#.###.#",
+		"#.###.#.###.#.#.###.#",
+		"#.###.#.#.#.#.#.###.#",
+		"#.....#.#..#..#.....#",
+		"#######.#.#.#.#######",
+		"........#.#..........",
+		"#.#####..#.#..#####..",
+		".##.##.#.#.########.#",
+		"#.#.####.##.###..###.",
+		"#.#..#...#.###..###..",
+		"...#.#####..###.....#",
+		"........#.#.#...##..#",
+		"#######....#..#...##.",
+		"#.....#.#....#.#.####",
+		"#.###.#.#..#..##....#",
+		"#.###.#.##..######...",
+		"#.###.#.##..#..#..#..",
+		"#.....#..##.##..###..",
+		"#######.##.##.#.#..#.",
+	}
+
+	code, err := qr.Encode([]byte("hello world"), qr.Medium)
+	if err != nil {
+		t.Log("Encode failed", err)
+		t.FailNow()
+	}
+
+	for y, row := range expected {
+		for x, module := range row {
+			if code.Dark(x, y) != (module == '#') {
+				t.Log(fmt.Sprintf("Unexpected module at [%d, %d]", x, y))
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func Test_RendersWithQuietZone(t *testing.T) {
+	code, _ := qr.Encode([]byte("encrypto"), qr.Low)
+
+	terminal := &bytes.Buffer{}
+	if err := qr.WriteTerminal(terminal, code, false); err != nil {
+		t.Log("WriteTerminal failed", err)
+		t.FailNow()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(terminal.String(), "\n"), "\n")
+	width := code.Size() + 2*qr.QuietZone
+	if len(lines) != (width+1)/2 || len([]rune(lines[0])) != width || strings.Trim(lines[0], "█") != "" {
+		t.Log("Expected a light border of full blocks around the terminal code")
+		t.Fail()
+	}
+
+	encoded := &bytes.Buffer{}
+	if err := qr.WritePNG(encoded, code, 3); err != nil {
+		t.Log("WritePNG failed", err)
+		t.FailNow()
+	}
+
+	img, err := png.Decode(encoded)
+	if err != nil {
+		t.Log("Could not decode the PNG", err)
+		t.FailNow()
+	}
+
+	if img.Bounds().Dx() != width*3 || img.Bounds().Dy() != width*3 {
+		t.Log(fmt.Sprintf("Unexpected image size [%v]", img.Bounds()))
+		t.Fail()
+	}
+}
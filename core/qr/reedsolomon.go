@@ -0,0 +1,50 @@
+package qr
+
+// multiply works in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1, the field used
+// by QR codes.
+func multiply(a byte, b byte) byte {
+	result := 0
+	x, y := int(a), int(b)
+	for i := 7; i >= 0; i-- {
+		result = (result << 1) ^ ((result >> 7) * 0x11d)
+		result ^= ((y >> uint(i)) & 1) * x
+	}
+
+	return byte(result)
+}
+
+// divisor returns the generator polynomial of the given degree, highest
+// coefficient first and without the leading 1.
+func divisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = multiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+
+		root = multiply(root, 0x02)
+	}
+
+	return result
+}
+
+func remainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+
+		for i, coefficient := range divisor {
+			result[i] ^= multiply(coefficient, factor)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,61 @@
+package qr
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// WriteTerminal draws two rows of modules per line with half block
+// characters. By default light modules are printed as blocks, which reads
+// correctly on the usual light-on-dark terminal; invert suits dark text on a
+// light background.
+func WriteTerminal(dst io.Writer, code Code, invert bool) error {
+	blocks := [4]string{"█", "▀", "▄", " "}
+	if invert {
+		blocks = [4]string{" ", "▄", "▀", "█"}
+	}
+
+	buffered := bufio.NewWriter(dst)
+	for y := -QuietZone; y < code.Size()+QuietZone; y += 2 {
+		for x := -QuietZone; x < code.Size()+QuietZone; x++ {
+			index := 0
+			if code.Dark(x, y) {
+				index |= 2
+			}
+			if code.Dark(x, y+1) {
+				index |= 1
+			}
+
+			buffered.WriteString(blocks[index])
+		}
+
+		buffered.WriteString("\n")
+	}
+
+	return buffered.Flush()
+}
+
+// WritePNG writes a black on white image with scale pixels to a module.
+func WritePNG(dst io.Writer, code Code, scale int) error {
+	if scale < 1 {
+		return fmt.Errorf("invalid scale [%d]", scale)
+	}
+
+	width := (code.Size() + 2*QuietZone) * scale
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, width, width), palette)
+
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			if code.Dark(x/scale-QuietZone, y/scale-QuietZone) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return png.Encode(dst, img)
+}
@@ -0,0 +1,133 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type Share struct {
+	X byte
+	Y []byte
+}
+
+// Arithmetic is in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1,
+// using log and exp tables built from the generator 3.
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	value := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = value
+		expTable[i+255] = value
+		logTable[value] = byte(i)
+
+		// multiply by the generator 3, which is x + 1
+		high := value & 0x80
+		doubled := value << 1
+		if high != 0 {
+			doubled ^= 0x1b
+		}
+		value ^= doubled
+	}
+}
+
+func multiply(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func divide(a byte, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+func evaluate(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = multiply(result, x) ^ coefficients[i]
+	}
+
+	return result
+}
+
+// Split produces total shares of secret, any threshold of which recover it.
+func Split(secret []byte, total int, threshold int) ([]Share, error) {
+	if threshold < 2 {
+		return nil, errors.New("threshold must be at least 2")
+	}
+
+	if total < threshold || total > 255 {
+		return nil, fmt.Errorf("total shares [%d] must be between the threshold [%d] and 255", total, threshold)
+	}
+
+	if len(secret) == 0 {
+		return nil, errors.New("secret must not be empty")
+	}
+
+	shares := make([]Share, total)
+	for i := range shares {
+		shares[i] = Share{X: byte(i + 1), Y: make([]byte, len(secret))}
+	}
+
+	coefficients := make([]byte, threshold)
+	for position, secretByte := range secret {
+		coefficients[0] = secretByte
+		if _, err := io.ReadFull(rand.Reader, coefficients[1:]); err != nil {
+			return nil, err
+		}
+
+		for i := range shares {
+			shares[i].Y[position] = evaluate(coefficients, shares[i].X)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine interpolates the shares at zero. Given fewer shares than the
+// threshold it returns an unrelated value, so callers need their own check
+// that the result is correct.
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+
+	length := len(shares[0].Y)
+	seen := map[byte]bool{}
+	for _, share := range shares {
+		if share.X == 0 || seen[share.X] {
+			return nil, fmt.Errorf("share [%d] is invalid or repeated", share.X)
+		}
+		if len(share.Y) != length {
+			return nil, errors.New("shares have different lengths")
+		}
+		seen[share.X] = true
+	}
+
+	secret := make([]byte, length)
+	for i, share := range shares {
+		basis := byte(1)
+		for j, other := range shares {
+			if i != j {
+				basis = multiply(basis, divide(other.X, other.X^share.X))
+			}
+		}
+
+		for position := range secret {
+			secret[position] ^= multiply(share.Y[position], basis)
+		}
+	}
+
+	return secret, nil
+}
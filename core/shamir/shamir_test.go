@@ -0,0 +1,86 @@
+package shamir_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"util.tim/encrypto/core/shamir"
+)
+
+var secret = []byte("a thirty two byte data key value")
+
+func splitForTest(t *testing.T, total int, threshold int) []shamir.Share {
+	shares, err := shamir.Split(secret, total, threshold)
+	if err != nil {
+		t.Log("Split failed", err)
+		t.FailNow()
+	}
+
+	return shares
+}
+
+func Test_AnyThresholdSubsetRecovers(t *testing.T) {
+	shares := splitForTest(t, 5, 3)
+
+	subsets := [][]int{{0, 1, 2}, {0, 2, 4}, {4, 3, 1}, {1, 2, 3, 4}, {0, 1, 2, 3, 4}}
+	for _, subset := range subsets {
+		chosen := []shamir.Share{}
+		for _, index := range subset {
+			chosen = append(chosen, shares[index])
+		}
+
+		combined, err := shamir.Combine(chosen)
+		if err != nil {
+			t.Log(fmt.Sprintf("Combine of %v failed", subset), err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(combined, secret) {
+			t.Log(fmt.Sprintf("Subset %v did not recover the secret", subset))
+			t.Fail()
+		}
+	}
+}
+
+func Test_FewerThanThresholdDoesNotRecover(t *testing.T) {
+	shares := splitForTest(t, 5, 3)
+
+	combined, err := shamir.Combine(shares[:2])
+	if err != nil {
+		t.Log("Combine failed", err)
+		t.FailNow()
+	}
+
+	if bytes.Equal(combined, secret) {
+		t.Log("Expected two of three shares not to recover the secret")
+		t.Fail()
+	}
+}
+
+func Test_SharesDoNotContainTheSecret(t *testing.T) {
+	for _, share := range splitForTest(t, 3, 2) {
+		if bytes.Equal(share.Y, secret) {
+			t.Log("Expected a share to differ from the secret")
+			t.Fail()
+		}
+	}
+}
+
+func Test_RejectsRepeatedShares(t *testing.T) {
+	shares := splitForTest(t, 3, 2)
+
+	if _, err := shamir.Combine([]shamir.Share{shares[0], shares[0]}); err == nil {
+		t.Log("Expected a repeated share to be rejected")
+		t.Fail()
+	}
+}
+
+func Test_RejectsInvalidParameters(t *testing.T) {
+	for _, parameters := range [][2]int{{3, 1}, {2, 3}, {256, 2}} {
+		if _, err := shamir.Split(secret, parameters[0], parameters[1]); err == nil {
+			t.Log(fmt.Sprintf("Expected total [%d] threshold [%d] to be rejected", parameters[0], parameters[1]))
+			t.Fail()
+		}
+	}
+}
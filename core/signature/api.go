@@ -0,0 +1,122 @@
+package signature
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"util.tim/encrypto/core/asymetric"
+)
+
+const (
+	blockType  = "ENCRYPTO SIGNATURE"
+	hashName   = "sha512"
+	domainLine = "encrypto detached signature v1\n"
+)
+
+type Signature struct {
+	Algorithm   string
+	Fingerprint string
+	Bytes       []byte
+}
+
+func NewHash() hash.Hash {
+	return sha512.New()
+}
+
+// signedMessage binds the digest to this scheme and its hash so a signature
+// can never be replayed as a signature over some other kind of message.
+func signedMessage(digest []byte) []byte {
+	return []byte(domainLine + hashName + "\n" + hex.EncodeToString(digest) + "\n")
+}
+
+func Digest(src io.Reader) ([]byte, error) {
+	hasher := NewHash()
+	if _, err := io.Copy(hasher, src); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
+}
+
+func SignDigest(digest []byte, signer asymetric.Signer) ([]byte, error) {
+	signature, err := signer.Sign(signedMessage(digest))
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: blockType,
+		Headers: map[string]string{
+			"Algorithm": signer.Algorithm(),
+			"Hash":      hashName,
+			"Key":       asymetric.Fingerprint(signer.PublicKeyBytes()),
+		},
+		Bytes: signature,
+	}), nil
+}
+
+func Sign(src io.Reader, signer asymetric.Signer) ([]byte, error) {
+	digest, err := Digest(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return SignDigest(digest, signer)
+}
+
+func Parse(encoded []byte) (Signature, error) {
+	block, _ := pem.Decode(encoded)
+	if block == nil || block.Type != blockType {
+		return Signature{}, errors.New("input is not an encrypto signature")
+	}
+
+	if block.Headers["Hash"] != hashName {
+		return Signature{}, fmt.Errorf("unsupported signature hash [%s]", block.Headers["Hash"])
+	}
+
+	return Signature{
+		Algorithm:   block.Headers["Algorithm"],
+		Fingerprint: block.Headers["Key"],
+		Bytes:       block.Bytes,
+	}, nil
+}
+
+// VerifyDigest returns the verifier whose key produced the signature.
+func VerifyDigest(digest []byte, encoded []byte, verifiers ...asymetric.Verifier) (asymetric.Verifier, error) {
+	signature, err := Parse(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, verifier := range verifiers {
+		if asymetric.Fingerprint(verifier.PublicKeyBytes()) != signature.Fingerprint {
+			continue
+		}
+
+		if verifier.Algorithm() != signature.Algorithm {
+			return nil, fmt.Errorf("signature algorithm [%s] does not match the key's [%s]", signature.Algorithm, verifier.Algorithm())
+		}
+
+		if err = verifier.Verify(signedMessage(digest), signature.Bytes); err != nil {
+			return nil, err
+		}
+
+		return verifier, nil
+	}
+
+	return nil, fmt.Errorf("none of the given keys made this signature, it was made by [%s]", signature.Fingerprint)
+}
+
+func Verify(src io.Reader, encoded []byte, verifiers ...asymetric.Verifier) (asymetric.Verifier, error) {
+	digest, err := Digest(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyDigest(digest, encoded, verifiers...)
+}
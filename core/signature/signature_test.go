@@ -0,0 +1,106 @@
+package signature_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"util.tim/encrypto/core/asymetric"
+	"util.tim/encrypto/core/signature"
+)
+
+type testKey struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+func (key *testKey) Algorithm() string {
+	return asymetric.SignatureEd25519
+}
+
+func (key *testKey) PublicKeyBytes() []byte {
+	return key.publicKey
+}
+
+func (key *testKey) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(key.privateKey, message), nil
+}
+
+func (key *testKey) Verify(message []byte, signature []byte) error {
+	if !ed25519.Verify(key.publicKey, message, signature) {
+		return errors.New("bad signature")
+	}
+
+	return nil
+}
+
+func newTestKey(seed byte) *testKey {
+	privateKey := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+
+	return &testKey{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+}
+
+func signForTest(t *testing.T, message string, key *testKey) []byte {
+	signed, err := signature.Sign(strings.NewReader(message), key)
+	if err != nil {
+		t.Log("Sign failed", err)
+		t.FailNow()
+	}
+
+	return signed
+}
+
+func Test_CanVerify(t *testing.T) {
+	key := newTestKey(1)
+	signed := signForTest(t, "release artifact", key)
+
+	verifier, err := signature.Verify(strings.NewReader("release artifact"), signed, newTestKey(2), key)
+	if err != nil {
+		t.Log("Verify failed", err)
+		t.FailNow()
+	}
+
+	if verifier != key {
+		t.Log("Expected the signing key to be reported")
+		t.Fail()
+	}
+}
+
+func Test_ModifiedContentFails(t *testing.T) {
+	key := newTestKey(1)
+	signed := signForTest(t, "release artifact", key)
+
+	if _, err := signature.Verify(strings.NewReader("release artifacT"), signed, key); err == nil {
+		t.Log("Expected modified content to fail verification")
+		t.Fail()
+	}
+}
+
+func Test_UnknownKeyFails(t *testing.T) {
+	signed := signForTest(t, "release artifact", newTestKey(1))
+
+	if _, err := signature.Verify(strings.NewReader("release artifact"), signed, newTestKey(2)); err == nil {
+		t.Log("Expected verification with an unrelated key to fail")
+		t.Fail()
+	}
+}
+
+func Test_SignatureRecordsTheKey(t *testing.T) {
+	key := newTestKey(1)
+
+	parsed, err := signature.Parse(signForTest(t, "release artifact", key))
+	if err != nil {
+		t.Log("Parse failed", err)
+		t.FailNow()
+	}
+
+	if parsed.Algorithm != asymetric.SignatureEd25519 || parsed.Fingerprint != asymetric.Fingerprint(key.publicKey) {
+		t.Log("Expected the algorithm and key fingerprint to be recorded")
+		t.Fail()
+	}
+}